@@ -0,0 +1,47 @@
+package jsonapi
+
+import (
+	"context"
+	"net/http"
+)
+
+// TokenProvider returns the bearer token for a request
+type TokenProvider func(ctx context.Context) (string, error)
+
+// Apply sets the Authorization header to the token the provider returns
+func (provider TokenProvider) Apply(request *http.Request) error {
+	token, err := provider(request.Context())
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+type basicAuth struct {
+	username string
+	password string
+}
+
+func (auth basicAuth) Apply(request *http.Request) error {
+	request.SetBasicAuth(auth.username, auth.password)
+	return nil
+}
+
+// SetBearerToken authenticates requests with a fixed bearer token
+func (jsonAPI *JSONAPI) SetBearerToken(token string) {
+	jsonAPI.SetTokenProvider(func(context.Context) (string, error) {
+		return token, nil
+	})
+}
+
+// SetBasicAuth authenticates requests with HTTP basic authentication
+func (jsonAPI *JSONAPI) SetBasicAuth(username, password string) {
+	jsonAPI.SetCredentialProvider(basicAuth{username: username, password: password})
+}
+
+// SetTokenProvider authenticates requests with a bearer token fetched from
+// provider for every request, its errors fail the request before it is sent
+func (jsonAPI *JSONAPI) SetTokenProvider(provider TokenProvider) {
+	jsonAPI.SetCredentialProvider(provider)
+}
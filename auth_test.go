@@ -0,0 +1,94 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// alternatingCredentials sends a bearer token and basic auth in turn
+type alternatingCredentials struct {
+	n int
+}
+
+func (credentials *alternatingCredentials) Apply(request *http.Request) error {
+	credentials.n++
+	if credentials.n%2 == 0 {
+		request.SetBasicAuth("u", "p")
+	} else {
+		request.Header.Set("Authorization", "Bearer t")
+	}
+	return nil
+}
+
+func TestCredentialProvider(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetCredentialProvider(&alternatingCredentials{})
+	onHTTPError, onInternalError := failOnError(t)
+	for i := 0; i < 2; i++ {
+		jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, onInternalError)
+	}
+	if !reflect.DeepEqual(received, []string{"Bearer t", "Basic dTpw"}) {
+		t.Fatalf("received %q", received)
+	}
+}
+
+func TestAuthHelpers(t *testing.T) {
+	var authorization string
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		hits++
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetBearerToken("abc")
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if authorization != "Bearer abc" {
+		t.Fatalf("Authorization = %q after SetBearerToken", authorization)
+	}
+	jsonAPI.SetBasicAuth("u", "p")
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if authorization != "Basic dTpw" {
+		t.Fatalf("Authorization = %q after SetBasicAuth", authorization)
+	}
+
+	n := 0
+	jsonAPI.SetTokenProvider(func(context.Context) (string, error) {
+		n++
+		return "t" + strconv.Itoa(n), nil
+	})
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if authorization != "Bearer t2" {
+		t.Fatalf("Authorization = %q, want a token per request", authorization)
+	}
+
+	errToken := errors.New("no token")
+	jsonAPI.SetTokenProvider(func(context.Context) (string, error) {
+		return "", errToken
+	})
+	sent := hits
+	var err error
+	jsonAPI.Get("/", nil, nil, func() {
+		t.Error("request without a token succeeded")
+	}, func(int, string, string) {
+		t.Error("request without a token was sent")
+	}, func(internalErr error) {
+		err = internalErr
+	})
+	if err != errToken || hits != sent {
+		t.Fatalf("err = %v after %d requests, want the token error before sending", err, hits-sent)
+	}
+}
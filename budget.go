@@ -0,0 +1,81 @@
+package jsonapi
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+)
+
+// ErrByteBudgetExceeded is returned for requests sent after the byte budget
+// set with SetByteBudget is used up
+var ErrByteBudgetExceeded = errors.New("jsonapi: byte budget exceeded")
+
+type byteBudget struct {
+	spent int64
+	limit int64
+}
+
+// SetByteBudget limits the request and response body bytes transferred by
+// all requests together to n, requests that would go over it fail with
+// ErrByteBudgetExceeded, 0 removes the limit and resets the count
+func (jsonAPI *JSONAPI) SetByteBudget(n int64) {
+	if n <= 0 {
+		jsonAPI.byteBudget = nil
+		return
+	}
+	jsonAPI.byteBudget = &byteBudget{limit: n}
+}
+
+// charge counts the request body against the budget, a streamed body of
+// unknown length is counted as it is read
+func (budget *byteBudget) charge(request *http.Request) error {
+	if request.ContentLength <= 0 && request.Body != nil && request.Body != http.NoBody {
+		request.Body = &chargedBody{request.Body, budget}
+		return budget.take(0)
+	}
+	return budget.take(request.ContentLength)
+}
+
+// take counts size bytes against the budget, unless they would go over it
+func (budget *byteBudget) take(size int64) error {
+	for {
+		spent := atomic.LoadInt64(&budget.spent)
+		if spent >= budget.limit || spent+size > budget.limit {
+			return ErrByteBudgetExceeded
+		}
+		if atomic.CompareAndSwapInt64(&budget.spent, spent, spent+size) {
+			return nil
+		}
+	}
+}
+
+// chargedBody counts a streamed request body against the budget as it is
+// read, failing the request once it goes over
+type chargedBody struct {
+	io.ReadCloser
+	budget *byteBudget
+}
+
+func (body *chargedBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	if n > 0 {
+		if budgetErr := body.budget.take(int64(n)); budgetErr != nil {
+			return 0, budgetErr
+		}
+	}
+	return n, err
+}
+
+// countingBody counts the response body bytes against the budget as they
+// are read
+type countingBody struct {
+	io.ReadCloser
+	budget *byteBudget
+}
+
+func (body *countingBody) Read(p []byte) (int, error) {
+	n, err := body.ReadCloser.Read(p)
+	atomic.AddInt64(&body.budget.spent, int64(n))
+	return n, err
+}
@@ -0,0 +1,65 @@
+package jsonapi
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetByteBudget(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetByteBudget(10)
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonAPI.Request().PostJSON("/", nil, "0123456789", nil); !errors.Is(err, ErrByteBudgetExceeded) {
+		t.Fatalf("POST over the budget: err = %v, want ErrByteBudgetExceeded", err)
+	}
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); !errors.Is(err, ErrByteBudgetExceeded) {
+		t.Fatalf("GET after the budget is spent: err = %v, want ErrByteBudgetExceeded", err)
+	}
+	if requests != 2 {
+		t.Fatalf("requests = %d, want 2", requests)
+	}
+
+	jsonAPI.SetByteBudget(0)
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatalf("after removing the budget: %v", err)
+	}
+}
+
+func TestByteBudgetStreamedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(ioutil.Discard, r.Body)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetByteBudget(100)
+	_, err := jsonAPI.Request().Method("POST").Body(strings.NewReader(strings.Repeat("x", 60))).Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent := jsonAPI.byteBudget.spent; spent != 60 {
+		t.Fatalf("spent = %d, want 60", spent)
+	}
+
+	_, err = jsonAPI.Request().Method("POST").Body(strings.NewReader(strings.Repeat("x", 60))).Send()
+	if !errors.Is(err, ErrByteBudgetExceeded) {
+		t.Fatalf("err = %v, want ErrByteBudgetExceeded", err)
+	}
+}
@@ -0,0 +1,30 @@
+package jsonapi
+
+import "time"
+
+// Clock tells the time for features that wait, such as hedging and polling,
+// so that tests can control it
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// SetClock sets the clock used instead of the real time, nil restores the
+// real time
+func (jsonAPI *JSONAPI) SetClock(clock Clock) {
+	jsonAPI.clock = clock
+}
+
+func (jsonAPI *JSONAPI) now() time.Time {
+	if jsonAPI.clock == nil {
+		return time.Now()
+	}
+	return jsonAPI.clock.Now()
+}
+
+func (jsonAPI *JSONAPI) after(d time.Duration) <-chan time.Time {
+	if jsonAPI.clock == nil {
+		return time.After(d)
+	}
+	return jsonAPI.clock.After(d)
+}
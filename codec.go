@@ -0,0 +1,79 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"encoding/xml"
+)
+
+// Codec serializes request bodies and decodes response bodies, it lets
+// formats other than JSON be used without the package depending on them,
+// the data passed to Unmarshal is reused once it returns and must be copied
+// to be kept
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// JSONCodec is the default Codec
+var JSONCodec Codec = jsonCodec{}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml"
+}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
+
+// XMLCodec encodes bodies as XML with encoding/xml
+var XMLCodec Codec = xmlCodec{}
+
+// SetFallbackCodec sets a codec that decodes response and error bodies the
+// codec in use fails to decode, such as XML errors from a JSON API, like any
+// Codec it must not keep the data it is given
+func (jsonAPI *JSONAPI) SetFallbackCodec(codec Codec) {
+	jsonAPI.fallbackCodec = codec
+}
+
+// SetCodec sets the codec used for request and response bodies, requests
+// sent with another codec carry its Content-Type and Accept headers
+func (jsonAPI *JSONAPI) SetCodec(codec Codec) {
+	jsonAPI.codec = codec
+}
+
+// SetCodec sets the codec used for this request's bodies instead of the
+// JSONAPI's
+func (r *Request) SetCodec(codec Codec) *Request {
+	r.codec = codec
+	return r
+}
+
+// bodyCodec returns the codec configured for the request, or nil when the
+// default JSON codec applies
+func (r *Request) bodyCodec() Codec {
+	if r.codec != nil {
+		return r.codec
+	}
+	return r.api.codec
+}
@@ -0,0 +1,135 @@
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// yamlCodec encodes flat structs as "key: value" lines, keyed by the yaml
+// tag of each field
+type yamlCodec struct{}
+
+func (yamlCodec) ContentType() string {
+	return "application/yaml"
+}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	var buffer bytes.Buffer
+	for i := 0; i < value.NumField(); i++ {
+		fmt.Fprintf(&buffer, "%s: %v\n", value.Type().Field(i).Tag.Get("yaml"), value.Field(i).Interface())
+	}
+	return buffer.Bytes(), nil
+}
+
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error {
+	value := reflect.ValueOf(v).Elem()
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		parts := strings.SplitN(line, ": ", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("yaml: bad line %q", line)
+		}
+		for i := 0; i < value.NumField(); i++ {
+			if value.Type().Field(i).Tag.Get("yaml") != parts[0] {
+				continue
+			}
+			field := value.Field(i)
+			switch field.Kind() {
+			case reflect.String:
+				field.SetString(parts[1])
+			case reflect.Int:
+				n, err := strconv.Atoi(parts[1])
+				if err != nil {
+					return err
+				}
+				field.SetInt(int64(n))
+			case reflect.Bool:
+				field.SetBool(parts[1] == "true")
+			}
+		}
+	}
+	return nil
+}
+
+type deployment struct {
+	Name     string `yaml:"name"`
+	Replicas int    `yaml:"replicas"`
+	Enabled  bool   `yaml:"enabled"`
+}
+
+func TestSetCodec(t *testing.T) {
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/yaml" || r.Header.Get("Accept") != "application/yaml" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		received = string(body)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	sent := deployment{Name: "api", Replicas: 3, Enabled: true}
+	var result deployment
+	_, err := jsonAPI.Request().Method("POST").SetCodec(yamlCodec{}).Body(sent).Into(&result).Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if received != "name: api\nreplicas: 3\nenabled: true\n" {
+		t.Fatalf("sent %q, want YAML", received)
+	}
+	if result != sent {
+		t.Fatalf("result = %+v, want %+v", result, sent)
+	}
+
+	jsonAPI.SetCodec(yamlCodec{})
+	sent = deployment{Name: "worker", Replicas: 1}
+	result = deployment{}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Post("/", nil, sent, &result, func() {}, onHTTPError, onInternalError)
+	if result != sent {
+		t.Fatalf("result = %+v, want %+v", result, sent)
+	}
+}
+
+func TestFallbackCodec(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.Header().Set("Content-Type", "text/xml")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<error><error>Boom</error><status>500</status><message>it broke</message></error>`))
+			return
+		}
+		w.Write([]byte(`<item><name>x</name></item>`))
+	}))
+	defer server.Close()
+
+	type item struct {
+		Name string `json:"name" xml:"name"`
+	}
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result item
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err == nil {
+		t.Fatal("XML decoded without a fallback codec")
+	}
+	jsonAPI.SetFallbackCodec(XMLCodec)
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err != nil || result.Name != "x" {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+
+	err := jsonAPI.Request().GetJSON("/error", nil, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Details.Message != "it broke" || httpErr.Details.Error != "Boom" {
+		t.Fatalf("err = %v, want the XML error decoded", err)
+	}
+}
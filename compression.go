@@ -0,0 +1,92 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// SetSniffCompression makes responses without a Content-Encoding header be
+// decompressed anyway when their body starts with the gzip magic bytes
+func (jsonAPI *JSONAPI) SetSniffCompression(sniffCompression bool) {
+	jsonAPI.sniffCompression = sniffCompression
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	body io.Closer
+}
+
+func (reader gzipReadCloser) Close() error {
+	reader.Reader.Close()
+	return reader.body.Close()
+}
+
+// decodeGzip decompresses responses with Content-Encoding: gzip that the
+// transport passed on compressed, such as when Accept-Encoding was set by
+// the caller or a custom transport is used
+func decodeGzip(response *http.Response) error {
+	if !strings.EqualFold(response.Header.Get("Content-Encoding"), "gzip") {
+		return nil
+	}
+
+	gzipReader, err := gzip.NewReader(response.Body)
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	response.Body = gzipReadCloser{Reader: gzipReader, body: response.Body}
+	response.Header.Del("Content-Encoding")
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write(data)
+	if err != nil {
+		return nil, err
+	}
+	err = writer.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func sniffGzip(response *http.Response) error {
+	if response.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	reader := bufio.NewReader(response.Body)
+	magic, err := reader.Peek(2)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	body := teeReadCloser{Reader: reader, Closer: response.Body}
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		response.Body = body
+		return nil
+	}
+
+	gzipReader, err := gzip.NewReader(body)
+	if err != nil {
+		return err
+	}
+
+	response.Body = gzipReadCloser{Reader: gzipReader, body: body}
+	response.ContentLength = -1
+	response.Uncompressed = true
+	return nil
+}
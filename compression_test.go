@@ -0,0 +1,89 @@
+package jsonapi
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSniffCompression(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.URL.Path == "/plain" {
+			w.Write([]byte(`{"a":2}`))
+			return
+		}
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(`{"a":1}`))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result map[string]int
+	var err error
+	jsonAPI.Get("/", nil, &result, func() {}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if err == nil {
+		t.Fatal("unlabelled gzip decoded without sniffing")
+	}
+
+	jsonAPI.SetSniffCompression(true)
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, &result, func() {}, onHTTPError, onInternalError)
+	if result["a"] != 1 {
+		t.Fatalf("gzip: result = %v", result)
+	}
+	jsonAPI.Get("/plain", nil, &result, func() {}, onHTTPError, onInternalError)
+	if result["a"] != 2 {
+		t.Fatalf("plain: result = %v", result)
+	}
+}
+
+func TestCompressRequests(t *testing.T) {
+	var contentEncoding, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentEncoding = r.Header.Get("Content-Encoding")
+		var reader io.Reader = r.Body
+		if contentEncoding == "gzip" {
+			gzipReader, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			reader = gzipReader
+		}
+		data, _ := ioutil.ReadAll(reader)
+		body = string(data)
+		if r.URL.Path == "/plain" {
+			w.Write([]byte(`{"a":2}`))
+			return
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		writer := gzip.NewWriter(w)
+		writer.Write([]byte(`{"a":1}`))
+		writer.Close()
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, CompressRequests: true}
+	var result struct{ A int }
+	err := jsonAPI.Request().SetHeader("Accept-Encoding", "gzip").
+		PostJSON("/", nil, map[string]int{"x": 1}, &result)
+	if err != nil || result.A != 1 || contentEncoding != "gzip" || body != `{"x":1}` {
+		t.Fatalf("err = %v, result = %+v, sent %q as %q", err, result, body, contentEncoding)
+	}
+	if err := jsonAPI.Request().PostJSON("/", nil, map[string]int{"x": 1}, &result); err != nil || result.A != 1 {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+
+	jsonAPI.CompressRequests = false
+	err = jsonAPI.Request().SetHeader("Accept-Encoding", "gzip").PostJSON("/plain", nil, []byte("raw"), &result)
+	if err != nil || result.A != 2 || contentEncoding != "" || body != "raw" {
+		t.Fatalf("err = %v, result = %+v, sent %q as %q", err, result, body, contentEncoding)
+	}
+}
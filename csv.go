@@ -0,0 +1,57 @@
+package jsonapi
+
+import (
+	"encoding/csv"
+	"io"
+	"net/url"
+)
+
+// RowCallback runs for every record of a CSV response, record is only valid
+// until the callback returns
+type RowCallback func(record []string) error
+
+// GetCSV request, reading a CSV response one record at a time, the first
+// record is skipped when hasHeader is set
+func (r *Request) GetCSV(url string, parameters url.Values, hasHeader bool,
+	onRow RowCallback, onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	r.method = "GET"
+	r.path = url
+	r.parameters = parameters
+	response, cancel := r.open(onHTTPError, onInternalError)
+	if response == nil {
+		return
+	}
+
+	defer cancel()
+	defer response.Body.Close()
+	if isRedirect(response.StatusCode) {
+		onSuccess()
+		return
+	}
+	reader := csv.NewReader(response.Body)
+	reader.ReuseRecord = true
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+
+		if hasHeader {
+			hasHeader = false
+			continue
+		}
+
+		err = onRow(record)
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+	}
+
+	onSuccess()
+}
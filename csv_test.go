@@ -0,0 +1,23 @@
+package jsonapi
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGetCSV(t *testing.T) {
+	server := jsonServer("id,name\n1,a\n2,\"b,c\"\n")
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var records []string
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().GetCSV("/", nil, true, func(record []string) error {
+		records = append(records, strings.Join(record, "|"))
+		return nil
+	}, func() {}, onHTTPError, onInternalError)
+	if !reflect.DeepEqual(records, []string{"1|a", "2|b,c"}) {
+		t.Fatalf("records = %q, want the rows after the header", records)
+	}
+}
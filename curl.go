@@ -0,0 +1,152 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// ParseCurl builds a Request from a simple curl command, supporting the URL,
+// -X, -H and -d flags and their long forms, the URL must start with BaseURL
+func (jsonAPI *JSONAPI) ParseCurl(cmd string) (*Request, error) {
+	args, err := splitCommand(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != 0 && args[0] == "curl" {
+		args = args[1:]
+	}
+
+	r := jsonAPI.Request()
+	var method, rawURL string
+	var data []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") {
+			if rawURL != "" {
+				return nil, fmt.Errorf("jsonapi: curl command has more than one URL")
+			}
+			rawURL = arg
+			continue
+		}
+
+		switch arg {
+		case "-s", "--silent":
+			continue
+		case "-X", "--request", "-H", "--header", "-d", "--data", "--data-raw",
+			"--data-binary", "--url":
+		default:
+			return nil, fmt.Errorf("jsonapi: unsupported curl flag %q", arg)
+		}
+
+		i++
+		if i == len(args) {
+			return nil, fmt.Errorf("jsonapi: curl flag %q has no value", arg)
+		}
+		value := args[i]
+
+		switch arg {
+		case "-X", "--request":
+			method = value
+		case "-H", "--header":
+			colon := strings.Index(value, ":")
+			if colon == -1 {
+				return nil, fmt.Errorf("jsonapi: invalid curl header %q", value)
+			}
+			name := textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(value[:colon]))
+			r.header.Add(name, strings.TrimSpace(value[colon+1:]))
+		case "--url":
+			rawURL = value
+		default:
+			data = append(data, value)
+		}
+	}
+
+	if rawURL == "" {
+		return nil, errors.New("jsonapi: curl command has no URL")
+	}
+	if !strings.HasPrefix(rawURL, jsonAPI.BaseURL) {
+		return nil, fmt.Errorf("jsonapi: curl URL %q does not start with BaseURL", rawURL)
+	}
+
+	path := strings.TrimPrefix(rawURL, jsonAPI.BaseURL)
+	if hash := strings.Index(path, "#"); hash != -1 {
+		path = path[:hash]
+	}
+	if question := strings.Index(path, "?"); question != -1 {
+		r.parameters, err = url.ParseQuery(path[question+1:])
+		if err != nil {
+			return nil, err
+		}
+		path = path[:question]
+	}
+	r.path = path
+
+	if data != nil {
+		r.rawBody = []byte(strings.Join(data, "&"))
+		if r.header.Get("Content-Type") == "" {
+			r.header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		if method == "" {
+			method = "POST"
+		}
+	}
+	if method != "" {
+		r.method = method
+	}
+	return r, nil
+}
+
+// splitCommand splits a command line into arguments following the quoting
+// rules of a POSIX shell
+func splitCommand(cmd string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	for i := 0; i < len(cmd); i++ {
+		c := cmd[i]
+		switch {
+		case c == '\\' && i+1 < len(cmd) && cmd[i+1] == '\n':
+			i++
+		case c == ' ' || c == '\t' || c == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		case c == '\'':
+			end := strings.IndexByte(cmd[i+1:], '\'')
+			if end == -1 {
+				return nil, errors.New("jsonapi: unterminated quote in curl command")
+			}
+			arg.WriteString(cmd[i+1 : i+1+end])
+			i += end + 1
+			inArg = true
+		case c == '"':
+			i++
+			for ; i < len(cmd) && cmd[i] != '"'; i++ {
+				if cmd[i] == '\\' && i+1 < len(cmd) && strings.IndexByte("\\\"$`", cmd[i+1]) != -1 {
+					i++
+				}
+				arg.WriteByte(cmd[i])
+			}
+			if i == len(cmd) {
+				return nil, errors.New("jsonapi: unterminated quote in curl command")
+			}
+			inArg = true
+		case c == '\\' && i+1 < len(cmd):
+			i++
+			arg.WriteByte(cmd[i])
+			inArg = true
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
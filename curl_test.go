@@ -0,0 +1,49 @@
+package jsonapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseCurl(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, r.Method+" "+r.URL.String()+" "+r.Header.Get("Content-Type")+
+			" "+r.Header.Get("X-A")+" "+string(body))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	for _, command := range []string{
+		"curl " + server.URL + "/users?page=2",
+		`curl -X PUT -H 'Content-Type: application/json' -H "x-a: b c" --data '{"a": "it''s"}' ` + server.URL + "/u/1",
+		"curl -d a=1 \\\n -d b=2 '" + server.URL + "/f'",
+	} {
+		request, err := jsonAPI.ParseCurl(command)
+		if err != nil {
+			t.Fatalf("%q: %v", command, err)
+		}
+		if _, err := request.Send(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{
+		"GET /users?page=2   ",
+		`PUT /u/1 application/json b c {"a": "its"}`,
+		"POST /f application/x-www-form-urlencoded  a=1&b=2",
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Fatalf("received %q, want %q", received, want)
+	}
+
+	if _, err := jsonAPI.ParseCurl("curl -F a=b " + server.URL); err == nil {
+		t.Error("unsupported flag parsed")
+	}
+	if _, err := jsonAPI.ParseCurl("curl http://other.example/"); err == nil {
+		t.Error("URL outside BaseURL parsed")
+	}
+}
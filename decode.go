@@ -0,0 +1,48 @@
+package jsonapi
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type base64JSON struct {
+	path         []string
+	responseBody interface{}
+}
+
+// Base64JSON wraps responseBody so that the base64 encoded JSON string found
+// at the dot separated path of the response body is decoded into it
+func Base64JSON(path string, responseBody interface{}) interface{} {
+	return &base64JSON{path: strings.Split(path, "."), responseBody: responseBody}
+}
+
+func (b *base64JSON) UnmarshalJSON(data []byte) error {
+	for _, key := range b.path {
+		var object map[string]json.RawMessage
+		err := json.Unmarshal(data, &object)
+		if err != nil {
+			return err
+		}
+
+		var ok bool
+		data, ok = object[key]
+		if !ok {
+			return fmt.Errorf("jsonapi: field %q not found in response body", key)
+		}
+	}
+
+	var encoded string
+	err := json.Unmarshal(data, &encoded)
+	if err != nil {
+		return err
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(decoded, b.responseBody)
+}
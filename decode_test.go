@@ -0,0 +1,45 @@
+package jsonapi
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBase64JSON(t *testing.T) {
+	payload := base64.StdEncoding.EncodeToString([]byte(`{"a":5}`))
+	server := jsonServer(`{"data":{"payload":"` + payload + `"}}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result struct{ A int }
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, Base64JSON("data.payload", &result), func() {}, onHTTPError, onInternalError)
+	if result.A != 5 {
+		t.Fatalf("result = %+v, want the decoded payload", result)
+	}
+
+	var err error
+	jsonAPI.Get("/", nil, Base64JSON("data.missing", &result), func() {
+		t.Error("missing field decoded")
+	}, onHTTPError, func(internalErr error) {
+		err = internalErr
+	})
+	if err == nil {
+		t.Fatal("missing field is not an error")
+	}
+}
+
+func TestBOM(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("\xef\xbb\xbf \r\n{\"a\":1}"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result struct{ A int }
+	if _, err := jsonAPI.Request().Into(&result).Send(); err != nil || result.A != 1 {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+}
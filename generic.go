@@ -0,0 +1,65 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+// StreamInto streams the elements of a top level JSON array response as T
+// values, the items channel must be read until it is closed, after which
+// the errors channel yields any error before being closed too, cancelling
+// ctx stops the stream when the items are no longer wanted
+func StreamInto[T any](ctx context.Context, jsonAPI *JSONAPI, url string,
+	parameters url.Values) (<-chan T, <-chan error) {
+	items := make(chan T)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		defer close(items)
+		var err error
+		request := jsonAPI.newRequest("GET", url, parameters, nil, nil).WithContext(ctx)
+		onHTTPError, onInternalError := request.errorCallbacks(&err)
+		request.streamArray(func(element json.RawMessage) error {
+			var item T
+			err := json.Unmarshal(element, &item)
+			if err != nil {
+				return err
+			}
+			select {
+			case items <- item:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, func() {}, onHTTPError, onInternalError)
+		if err != nil {
+			errs <- err
+		}
+	}()
+	return items, errs
+}
+
+// Get request, returning the response body decoded as a T, HTTP errors are
+// returned as an *HTTPError
+func Get[T any](jsonAPI *JSONAPI, url string, parameters url.Values) (T, *http.Response, error) {
+	return send[T](jsonAPI.newRequest("GET", url, parameters, nil, nil))
+}
+
+// Post request, returning the response body decoded as a T, HTTP errors are
+// returned as an *HTTPError
+func Post[T any](jsonAPI *JSONAPI, url string, parameters url.Values,
+	requestBody interface{}) (T, *http.Response, error) {
+	return send[T](jsonAPI.newRequest("POST", url, parameters, requestBody, nil))
+}
+
+func send[T any](r *Request) (T, *http.Response, error) {
+	var result T
+	response, err := r.Into(&result).Send()
+	if err != nil {
+		var zero T
+		return zero, response, err
+	}
+	return result, response, nil
+}
@@ -0,0 +1,109 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamInto(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"n":1},{"n":2},{"n":3}]`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	items, errs := StreamInto[struct{ N int }](context.Background(), jsonAPI, "/", nil)
+	sum := 0
+	for item := range items {
+		sum += item.N
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if sum != 6 {
+		t.Fatalf("sum = %d, want 6", sum)
+	}
+}
+
+func TestGetAndPost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bad":
+			w.Write([]byte(`{"n":"x"}`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			body, _ := ioutil.ReadAll(r.Body)
+			fmt.Fprintf(w, `{"n":%d,"m":%q}`, len(body), r.Method)
+		}
+	}))
+	defer server.Close()
+
+	type result struct {
+		N int
+		M string
+	}
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	value, response, err := Get[result](jsonAPI, "/", nil)
+	if err != nil || value.M != "GET" || response.StatusCode != http.StatusOK {
+		t.Fatalf("Get: err = %v, value = %+v", err, value)
+	}
+	value, _, err = Post[result](jsonAPI, "/", nil, map[string]int{"a": 1})
+	if err != nil || value.M != "POST" || value.N != 7 {
+		t.Fatalf("Post: err = %v, value = %+v", err, value)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if _, _, err := Get[result](jsonAPI, "/bad", nil); !errors.As(err, &typeErr) {
+		t.Fatalf("err = %v, want an UnmarshalTypeError", err)
+	}
+	var httpErr *HTTPError
+	if _, _, err := Get[*result](jsonAPI, "/missing", nil); !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v, want an HTTPError", err)
+	}
+}
+
+func TestStreamIntoCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[1,2,3,4,5]`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	items, errs := StreamInto[int](ctx, jsonAPI, "/", nil)
+	if item := <-items; item != 1 {
+		t.Fatalf("item = %d, want 1", item)
+	}
+	cancel()
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("err = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not stop after cancel")
+	}
+}
+
+func TestStreamIntoNotModified(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	items, errs := StreamInto[int](context.Background(), jsonAPI, "/not-modified", nil)
+	for range items {
+		t.Error("item decoded")
+	}
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/dankeroni/jsonapi
+
+go 1.18
@@ -0,0 +1,105 @@
+package jsonapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SetHedging makes GET requests send up to maxExtra identical requests, one
+// every delay while none has responded, and use whichever responds first,
+// requests with a body that can only be read once are not hedged
+func (jsonAPI *JSONAPI) SetHedging(delay time.Duration, maxExtra int) {
+	jsonAPI.hedgingDelay = delay
+	jsonAPI.hedgingMaxExtra = maxExtra
+}
+
+type hedgedResult struct {
+	index    int
+	response *http.Response
+	err      error
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (body cancelOnClose) Close() error {
+	err := body.ReadCloser.Close()
+	body.cancel()
+	return err
+}
+
+// hedgeable reports whether request can be sent more than once, every copy
+// needs its own body so bodies without GetBody are sent only once
+func hedgeable(request *http.Request) bool {
+	return request.Body == nil || request.Body == http.NoBody || request.GetBody != nil
+}
+
+func (jsonAPI *JSONAPI) doHedged(request *http.Request) (*http.Response, error) {
+	results := make(chan hedgedResult, jsonAPI.hedgingMaxExtra+1)
+	var cancels []context.CancelFunc
+	launch := func() {
+		ctx, cancel := context.WithCancel(request.Context())
+		index := len(cancels)
+		cancels = append(cancels, cancel)
+		go func() {
+			hedged := request.Clone(ctx)
+			if request.GetBody != nil {
+				body, err := request.GetBody()
+				if err != nil {
+					results <- hedgedResult{index, nil, err}
+					return
+				}
+				hedged.Body = body
+			}
+			response, err := jsonAPI.httpClient().Do(hedged)
+			results <- hedgedResult{index, response, err}
+		}()
+	}
+
+	launch()
+	hedge := jsonAPI.after(jsonAPI.hedgingDelay)
+	pending := 1
+	for {
+		select {
+		case <-hedge:
+			hedge = nil
+			if len(cancels) <= jsonAPI.hedgingMaxExtra {
+				launch()
+				pending++
+				hedge = jsonAPI.after(jsonAPI.hedgingDelay)
+			}
+		case result := <-results:
+			pending--
+			if result.err != nil && pending > 0 {
+				continue
+			}
+
+			for index, cancel := range cancels {
+				if index != result.index {
+					cancel()
+				}
+			}
+			go discardHedged(results, pending)
+			if result.err != nil {
+				cancels[result.index]()
+				return nil, result.err
+			}
+
+			result.response.Body = cancelOnClose{result.response.Body, cancels[result.index]}
+			return result.response, nil
+		}
+	}
+}
+
+func discardHedged(results <-chan hedgedResult, pending int) {
+	for ; pending > 0; pending-- {
+		result := <-results
+		if result.err == nil {
+			result.response.Body.Close()
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package jsonapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHedging(t *testing.T) {
+	var attempts int32
+	cancelled := make(chan bool, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			select {
+			case <-r.Context().Done():
+				cancelled <- true
+				return
+			case <-time.After(2 * time.Second):
+			}
+			w.Write([]byte(`{"w":"slow"}`))
+			return
+		}
+		w.Write([]byte(`{"w":"fast"}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetHedging(50*time.Millisecond, 1)
+	var result map[string]string
+	start := time.Now()
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, &result, func() {}, onHTTPError, onInternalError)
+	if result["w"] != "fast" || time.Since(start) > time.Second {
+		t.Fatalf("result = %v after %v, want the hedged attempt", result, time.Since(start))
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("slow attempt not cancelled")
+	}
+}
+
+func TestHedgingWithCookies(t *testing.T) {
+	var mu sync.Mutex
+	var cookies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "s", Value: "x"})
+			return
+		}
+
+		mu.Lock()
+		cookies = append(cookies, r.Header.Get("Cookie"))
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.EnableCookies()
+	if err := jsonAPI.Request().GetJSON("/login", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	jsonAPI.SetHedging(time.Millisecond, 3)
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(cookies) < 2 {
+		t.Fatalf("got %d attempts, want hedged ones", len(cookies))
+	}
+	for _, cookie := range cookies {
+		if cookie != "s=x" {
+			t.Fatalf("Cookie = %q, want s=x", cookie)
+		}
+	}
+}
+
+func TestHedgingBodies(t *testing.T) {
+	var mu sync.Mutex
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		bodies = append(bodies, string(body))
+		first := len(bodies) == 1
+		mu.Unlock()
+		if first {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetHedging(20*time.Millisecond, 1)
+	if _, err := jsonAPI.Request().Path("/").Body(map[string]int{"a": 1}).Send(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	hedged := bodies
+	bodies = nil
+	mu.Unlock()
+	if !reflect.DeepEqual(hedged, []string{`{"a":1}`, `{"a":1}`}) {
+		t.Fatalf("bodies = %q, want the body sent with both attempts", hedged)
+	}
+
+	if _, err := jsonAPI.Request().Path("/").Body(strings.NewReader("once")).Send(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(bodies, []string{"once"}) {
+		t.Fatalf("bodies = %q, want an io.Reader body sent once without hedging", bodies)
+	}
+}
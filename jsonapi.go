@@ -3,73 +3,329 @@ package jsonapi
 import (
 	"bytes"
 	"encoding/json"
-	"io/ioutil"
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Error struct
 type Error struct {
-	Error   string `json:"error"`
-	Status  int    `json:"status"`
-	Message string `json:"message"`
+	Error   string `json:"error" xml:"error"`
+	Status  int    `json:"status" xml:"status"`
+	Message string `json:"message" xml:"message"`
+	// Type and Instance are the problem type and occurrence URIs of an
+	// application/problem+json response, whose title and detail are put in
+	// Error and Message
+	Type     string `json:"type,omitempty" xml:"type,omitempty"`
+	Instance string `json:"instance,omitempty" xml:"instance,omitempty"`
 }
 
-// JSONAPI struct
+// HTTPError is the error returned for HTTP error responses, Details holds
+// the decoded error body
+type HTTPError struct {
+	Details Error
+	// Err is the error the status is mapped to with SetStatusErrorMap
+	Err error
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return "jsonapi: " + e.Details.Error
+}
+
+// Unwrap returns Err so errors.Is matches the mapped error
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError is the error returned for 429 Too Many Requests responses,
+// RetryAfter is the wait asked for by their Retry-After header, 0 if none
+type RateLimitError struct {
+	*HTTPError
+	RetryAfter time.Duration
+}
+
+// Unwrap returns the HTTPError so errors.As still finds it
+func (e *RateLimitError) Unwrap() error {
+	return e.HTTPError
+}
+
+// ErrorDecoder decodes an error response body into Error, fields it leaves
+// untouched keep their defaults, body is reused once the decoder returns and
+// must be copied to be kept
+type ErrorDecoder func(body []byte, Error *Error) error
+
+// XMLErrorDecoder decodes error bodies with error, status and message
+// elements
+func XMLErrorDecoder(body []byte, Error *Error) error {
+	return xml.Unmarshal(body, Error)
+}
+
+// JSONAPI struct, it is safe for concurrent use as long as its fields are
+// not changed while requests are sent, of the setters only SetHeader,
+// DelHeader, SetHostHeaders, RegisterErrorDecoder, PropagateContextHeader,
+// AddPlugin and UseResponse may be called then, the others are for setting
+// it up
 type JSONAPI struct {
 	BaseURL string
+	// Headers are sent with every request, use SetHeader and DelHeader to
+	// change them while requests are being sent
 	Headers map[string]string
+	// Client sends the requests instead of the shared package client when
+	// set, SetResolver and the dial timeouts only apply to it when its
+	// Transport is nil
+	Client *http.Client
+	// RetryPolicy retries failed requests when set
+	RetryPolicy *RetryPolicy
+	// CompressRequests gzips serialized request bodies and sends them with
+	// Content-Encoding: gzip
+	CompressRequests bool
+	// ErrorParser extracts the message and error passed to the HTTP error
+	// callback from error bodies instead of the built-in decoding, empty
+	// results keep the body and status line, decoders registered with
+	// RegisterErrorDecoder still take precedence, body is reused once the
+	// parser returns and must be copied to be kept
+	ErrorParser func(statusCode int, body []byte) (message, errorString string)
+	// OnRequest runs right before a request is sent, with a copy whose body
+	// can be read without consuming the one that is sent
+	OnRequest func(request *http.Request)
+	// OnResponse runs right after a response is received, with the time it
+	// took, before its body is read
+	OnResponse func(response *http.Response, elapsed time.Duration)
+
+	mu                 sync.RWMutex
+	credentialProvider CredentialProvider
+	maxJSONDepth       int
+	hostHeaders        map[string]map[string]string
+	responseTee        *lockedWriter
+	hedgingDelay       time.Duration
+	hedgingMaxExtra    int
+	dialer             *net.Dialer
+	transport          *http.Transport
+	transportClient    *http.Client
+	plugins            []Plugin
+	responseMiddleware []ResponseMiddleware
+	onWarning          WarningCallback
+	paramsAsForm       bool
+	dropEmptyParams    bool
+	sniffCompression   bool
+	errorDecoders      map[string]ErrorDecoder
+	codec              Codec
+	clock              Clock
+	requestIDHeader    string
+	requestIDGenerator func() string
+	recorder           Recorder
+	deadlineHeader     string
+	byteBudget         *byteBudget
+	statusErrors       map[int]error
+	requestValidator   func(requestBody interface{}) error
+	acceptLanguage     string
+	jsonpCallback      string
+	strictMethodBodies bool
+	onUnexpectedBody   UnexpectedBodyCallback
+	bodyReader         func(response *http.Response) ([]byte, error)
+	jar                http.CookieJar
+	contextHeaders     []contextHeader
+	fallbackCodec      Codec
+	retryBodyPredicate func(body []byte) bool
+}
+
+// CredentialProvider applies authentication to every outgoing request
+type CredentialProvider interface {
+	Apply(request *http.Request) error
 }
 
 // SuccessCallback runs on a successfull request and parse
 type SuccessCallback func()
 
+// SuccessCallbackV2 runs on a successfull request and parse with the
+// response status code and headers
+type SuccessCallbackV2 func(statusCode int, headers http.Header)
+
 // HTTPErrorCallback runs on a errored HTTP request
 type HTTPErrorCallback func(statusCode int, statusMessage, errorMessage string)
 
 // InternalErrorCallback runs on an internal error
 type InternalErrorCallback func(error)
 
-var client = &http.Client{}
+// SetHeader sets a header sent with every request
+func (jsonAPI *JSONAPI) SetHeader(name, value string) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	headers := make(map[string]string, len(jsonAPI.Headers)+1)
+	for key, value := range jsonAPI.Headers {
+		headers[key] = value
+	}
+	headers[name] = value
+	jsonAPI.Headers = headers
+}
 
-func (jsonAPI *JSONAPI) request(verb, url string, parameters url.Values,
-	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
-	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
-	url = jsonAPI.BaseURL + url + "?" + parameters.Encode()
-	var request *http.Request
-	var err error
-	if requestBody != nil {
-		serializedRequestBody, err := json.Marshal(requestBody)
-		if err != nil {
-			onInternalError(err)
-			return
+// DelHeader removes a header set with SetHeader or in Headers
+func (jsonAPI *JSONAPI) DelHeader(name string) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	headers := make(map[string]string, len(jsonAPI.Headers))
+	for key, value := range jsonAPI.Headers {
+		if key != name {
+			headers[key] = value
 		}
-
-		serializedRequestBodyReader := bytes.NewReader(serializedRequestBody)
-		request, err = http.NewRequest(verb, url, serializedRequestBodyReader)
-	} else {
-		request, err = http.NewRequest(verb, url, nil)
 	}
-	if err != nil {
-		onInternalError(err)
-		return
+	jsonAPI.Headers = headers
+}
+
+// hooks returns the headers, plugins and response middleware as they are
+// right now, none of them may be modified
+func (jsonAPI *JSONAPI) hooks() (map[string]string, []Plugin, []ResponseMiddleware) {
+	jsonAPI.mu.RLock()
+	defer jsonAPI.mu.RUnlock()
+	return jsonAPI.Headers, jsonAPI.plugins, jsonAPI.responseMiddleware
+}
+
+// SetMaxJSONDepth limits how deeply nested a response body may be, 0 means
+// unlimited
+func (jsonAPI *JSONAPI) SetMaxJSONDepth(depth int) {
+	jsonAPI.maxJSONDepth = depth
+}
+
+// SetHostHeaders sets headers sent instead of the matching Headers on
+// requests to host, with or without a port
+func (jsonAPI *JSONAPI) SetHostHeaders(host string, headers map[string]string) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	hostHeaders := make(map[string]map[string]string, len(jsonAPI.hostHeaders)+1)
+	for key, value := range jsonAPI.hostHeaders {
+		hostHeaders[key] = value
 	}
+	hostHeaders[host] = headers
+	jsonAPI.hostHeaders = hostHeaders
+}
 
-	for name, value := range jsonAPI.Headers {
-		request.Header.Add(name, value)
+// headersForHost returns the headers set with SetHostHeaders for the host of
+// url, with its port or else without it
+func (jsonAPI *JSONAPI) headersForHost(url *url.URL) map[string]string {
+	jsonAPI.mu.RLock()
+	defer jsonAPI.mu.RUnlock()
+	headers, ok := jsonAPI.hostHeaders[url.Host]
+	if !ok {
+		headers = jsonAPI.hostHeaders[url.Hostname()]
 	}
-	response, err := client.Do(request)
-	if err != nil {
-		onInternalError(err)
+	return headers
+}
+
+// SetResponseTee copies every response body to writer as it is read, writes
+// are serialized but bodies of concurrent requests may interleave
+func (jsonAPI *JSONAPI) SetResponseTee(writer io.Writer) {
+	if writer == nil {
+		jsonAPI.responseTee = nil
 		return
 	}
+	jsonAPI.responseTee = &lockedWriter{writer: writer}
+}
 
-	if response.StatusCode >= 300 {
-		handleHTTPError(response, onHTTPError, onInternalError)
-		return
+// SetParamsAsForm makes POST, PUT and PATCH requests without a request body
+// send their parameters as a form encoded body instead of in the URL, when
+// there is a request body the parameters always stay in the URL
+func (jsonAPI *JSONAPI) SetParamsAsForm(paramsAsForm bool) {
+	jsonAPI.paramsAsForm = paramsAsForm
+}
+
+// SetStrictMethodBodies makes GET and HEAD requests with a body fail with
+// ErrUnexpectedBody, by default they are sent and reported to the callback
+// set with OnUnexpectedBody
+func (jsonAPI *JSONAPI) SetStrictMethodBodies(strict bool) {
+	jsonAPI.strictMethodBodies = strict
+}
+
+// UnexpectedBodyCallback runs for GET and HEAD requests sent with a body
+type UnexpectedBodyCallback func(method, path string)
+
+// OnUnexpectedBody sets a callback that runs for GET and HEAD requests with a
+// body when SetStrictMethodBodies is not enabled
+func (jsonAPI *JSONAPI) OnUnexpectedBody(onUnexpectedBody UnexpectedBodyCallback) {
+	jsonAPI.onUnexpectedBody = onUnexpectedBody
+}
+
+// SetBodyReader sets how response bodies are read before they are decoded,
+// instead of reading response.Body to the end, the body is closed after
+func (jsonAPI *JSONAPI) SetBodyReader(reader func(response *http.Response) ([]byte, error)) {
+	jsonAPI.bodyReader = reader
+}
+
+// SetDropEmptyParams makes requests leave out parameters whose values are
+// all empty instead of sending them as key=
+func (jsonAPI *JSONAPI) SetDropEmptyParams(dropEmptyParams bool) {
+	jsonAPI.dropEmptyParams = dropEmptyParams
+}
+
+// RegisterErrorDecoder sets the decoder used for error responses with the
+// given media type, such as application/xml, instead of decoding them as JSON
+func (jsonAPI *JSONAPI) RegisterErrorDecoder(mediaType string, decoder ErrorDecoder) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	errorDecoders := make(map[string]ErrorDecoder, len(jsonAPI.errorDecoders)+1)
+	for key, value := range jsonAPI.errorDecoders {
+		errorDecoders[key] = value
 	}
+	errorDecoders[mediaType] = decoder
+	jsonAPI.errorDecoders = errorDecoders
+}
+
+func (jsonAPI *JSONAPI) errorDecoder(mediaType string) (ErrorDecoder, bool) {
+	jsonAPI.mu.RLock()
+	defer jsonAPI.mu.RUnlock()
+	decoder, ok := jsonAPI.errorDecoders[mediaType]
+	return decoder, ok
+}
+
+// SetDeadlineHeader makes requests whose context has a deadline carry the
+// milliseconds left until it in the header name, such as X-Server-Timeout
+func (jsonAPI *JSONAPI) SetDeadlineHeader(name string) {
+	jsonAPI.deadlineHeader = name
+}
 
-	handleSuccess(response, responseBody, onSuccess, onInternalError)
+// SetStatusErrorMap maps response statuses to errors, an HTTPError for a
+// mapped status wraps its error
+func (jsonAPI *JSONAPI) SetStatusErrorMap(statusErrors map[int]error) {
+	jsonAPI.statusErrors = statusErrors
+}
+
+// SetRequestValidator sets a validator that runs on request bodies before
+// they are serialized, an error fails the request without sending it
+func (jsonAPI *JSONAPI) SetRequestValidator(validator func(requestBody interface{}) error) {
+	jsonAPI.requestValidator = validator
+}
+
+// SetCredentialProvider sets the provider used to authenticate requests
+func (jsonAPI *JSONAPI) SetCredentialProvider(provider CredentialProvider) {
+	jsonAPI.credentialProvider = provider
+}
+
+var client = &http.Client{}
+
+// ErrMaxJSONDepth is returned when a response body is nested deeper than
+// the limit set with SetMaxJSONDepth
+var ErrMaxJSONDepth = errors.New("jsonapi: maximum JSON depth exceeded")
+
+type discardBody struct{}
+
+// DiscardBody can be passed as responseBody to read the response body
+// without decoding it
+var DiscardBody interface{} = &discardBody{}
+
+func (jsonAPI *JSONAPI) request(verb, url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	jsonAPI.newRequest(verb, url, parameters, requestBody, responseBody).execute(
+		onSuccess, onHTTPError, onInternalError)
 }
 
 // Get request
@@ -96,6 +352,14 @@ func (jsonAPI *JSONAPI) Post(url string, parameters url.Values,
 		onHTTPError, onInternalError)
 }
 
+// Patch request
+func (jsonAPI *JSONAPI) Patch(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	jsonAPI.request("PATCH", url, parameters, requestBody, responseBody, onSuccess,
+		onHTTPError, onInternalError)
+}
+
 // Delete request
 func (jsonAPI *JSONAPI) Delete(url string, parameters url.Values,
 	responseBody interface{}, onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
@@ -104,16 +368,96 @@ func (jsonAPI *JSONAPI) Delete(url string, parameters url.Values,
 		onHTTPError, onInternalError)
 }
 
-func handleSuccess(response *http.Response, data interface{}, onSuccess SuccessCallback,
+// Head request, onSuccess gets the status code and headers as there is no
+// body
+func (jsonAPI *JSONAPI) Head(url string, parameters url.Values,
+	onSuccess SuccessCallbackV2, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	jsonAPI.Request().Head(url, parameters, onSuccess, onHTTPError, onInternalError)
+}
+
+// Options request, onSuccess gets the status code and headers such as Allow
+func (jsonAPI *JSONAPI) Options(url string, parameters url.Values,
+	responseBody interface{}, onSuccess SuccessCallbackV2,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	jsonAPI.Request().Options(url, parameters, responseBody, onSuccess, onHTTPError,
+		onInternalError)
+}
+
+func (jsonAPI *JSONAPI) requestWithTimeout(timeout time.Duration, verb, url string,
+	parameters url.Values, requestBody interface{}, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	r := jsonAPI.newRequest(verb, url, parameters, requestBody, responseBody)
+	r.timeout = timeout
+	r.execute(onSuccess, onHTTPError, onInternalError)
+}
+
+// GetWithTimeout request, failing with an internal error after timeout
+func (jsonAPI *JSONAPI) GetWithTimeout(timeout time.Duration, url string,
+	parameters url.Values, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	jsonAPI.requestWithTimeout(timeout, "GET", url, parameters, nil, responseBody,
+		onSuccess, onHTTPError, onInternalError)
+}
+
+// PutWithTimeout request, failing with an internal error after timeout
+func (jsonAPI *JSONAPI) PutWithTimeout(timeout time.Duration, url string,
+	parameters url.Values, requestBody interface{}, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	jsonAPI.requestWithTimeout(timeout, "PUT", url, parameters, requestBody,
+		responseBody, onSuccess, onHTTPError, onInternalError)
+}
+
+// PostWithTimeout request, failing with an internal error after timeout
+func (jsonAPI *JSONAPI) PostWithTimeout(timeout time.Duration, url string,
+	parameters url.Values, requestBody interface{}, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
 	onInternalError InternalErrorCallback) {
-	body, err := body(response)
+	jsonAPI.requestWithTimeout(timeout, "POST", url, parameters, requestBody,
+		responseBody, onSuccess, onHTTPError, onInternalError)
+}
+
+// DeleteWithTimeout request, failing with an internal error after timeout
+func (jsonAPI *JSONAPI) DeleteWithTimeout(timeout time.Duration, url string,
+	parameters url.Values, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	jsonAPI.requestWithTimeout(timeout, "DELETE", url, parameters, nil, responseBody,
+		onSuccess, onHTTPError, onInternalError)
+}
+
+func (jsonAPI *JSONAPI) handleSuccess(response *http.Response, data interface{},
+	codec Codec, onSuccess SuccessCallback, onInternalError InternalErrorCallback) {
+	buffer, err := jsonAPI.body(response)
 	if err != nil {
 		onInternalError(err)
 		return
 	}
 
-	if len(body) != 0 {
-		err = json.Unmarshal(body, &data)
+	defer releaseBody(buffer)
+	body := trimLeading(buffer.Bytes())
+
+	if len(body) != 0 && data != nil && data != DiscardBody {
+		if codec == nil {
+			codec = JSONCodec
+		}
+		if jsonAPI.jsonpCallback != "" && codec == JSONCodec {
+			body = stripJSONP(body, jsonAPI.jsonpCallback)
+		}
+		if jsonAPI.maxJSONDepth > 0 && codec == JSONCodec {
+			err = checkJSONDepth(body, jsonAPI.maxJSONDepth)
+			if err != nil {
+				onInternalError(err)
+				return
+			}
+		}
+
+		err = codec.Unmarshal(body, data)
+		if err != nil && jsonAPI.fallbackCodec != nil &&
+			jsonAPI.fallbackCodec.Unmarshal(body, data) == nil {
+			err = nil
+		}
 		if err != nil {
 			onInternalError(err)
 			return
@@ -123,24 +467,157 @@ func handleSuccess(response *http.Response, data interface{}, onSuccess SuccessC
 	onSuccess()
 }
 
-func handleHTTPError(response *http.Response, onHTTPError HTTPErrorCallback,
-	onInternalError InternalErrorCallback) {
-	body, err := body(response)
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// trimLeading drops a UTF-8 byte order mark and whitespace some servers
+// emit in front of the document
+func trimLeading(body []byte) []byte {
+	return bytes.TrimLeft(bytes.TrimPrefix(body, utf8BOM), " \t\r\n")
+}
+
+// handleHTTPError decodes the error body into details and passes it to
+// onHTTPError
+func (jsonAPI *JSONAPI) handleHTTPError(response *http.Response, details *Error,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	buffer, err := jsonAPI.body(response)
 	if err != nil {
 		onInternalError(err)
 		return
 	}
 
+	defer releaseBody(buffer)
+	body := buffer.Bytes()
+
 	var Error Error
 	Error.Status = response.StatusCode
 	Error.Message = string(body[:])
 	Error.Error = response.Status
-	json.Unmarshal(body, &Error)
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if decoder, ok := jsonAPI.errorDecoder(mediaType); ok {
+		decoder(body, &Error)
+	} else if jsonAPI.ErrorParser != nil {
+		message, errorString := jsonAPI.ErrorParser(response.StatusCode, body)
+		if message != "" {
+			Error.Message = message
+		}
+		if errorString != "" {
+			Error.Error = errorString
+		}
+	} else if mediaType == "application/problem+json" {
+		parseProblem(body, &Error)
+	} else if !strings.HasPrefix(mediaType, "text/") {
+		err = json.Unmarshal(body, &Error)
+		if err != nil && jsonAPI.fallbackCodec != nil {
+			jsonAPI.fallbackCodec.Unmarshal(body, &Error)
+		}
+	} else if jsonAPI.fallbackCodec != nil {
+		jsonAPI.fallbackCodec.Unmarshal(body, &Error)
+	}
+	*details = Error
 	onHTTPError(Error.Status, Error.Message, Error.Error)
 }
 
-func body(response *http.Response) ([]byte, error) {
-	body, err := ioutil.ReadAll(response.Body)
+// problem is an RFC 7807 problem details object
+type problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail"`
+	Instance string `json:"instance"`
+}
+
+func parseProblem(body []byte, Error *Error) {
+	var problem problem
+	if json.Unmarshal(body, &problem) != nil {
+		return
+	}
+
+	if problem.Status != 0 {
+		Error.Status = problem.Status
+	}
+	if problem.Title != "" {
+		Error.Error = problem.Title
+		Error.Message = problem.Title
+	}
+	if problem.Detail != "" {
+		Error.Message = problem.Detail
+	}
+	Error.Type = problem.Type
+	Error.Instance = problem.Instance
+}
+
+func checkJSONDepth(body []byte, maxDepth int) error {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch token {
+		case json.Delim('['), json.Delim('{'):
+			depth++
+			if depth > maxDepth {
+				return ErrMaxJSONDepth
+			}
+		case json.Delim(']'), json.Delim('}'):
+			depth--
+		}
+	}
+}
+
+type lockedWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.writer.Write(p)
+}
+
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// bodyPoolMaxSize is the largest buffer kept for reuse after reading a body
+const bodyPoolMaxSize = 1 << 20
+
+var bodyPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// body reads the response body into a pooled buffer, which must be passed
+// to releaseBody once the bytes are no longer used
+func (jsonAPI *JSONAPI) body(response *http.Response) (*bytes.Buffer, error) {
+	buffer := bodyPool.Get().(*bytes.Buffer)
+	buffer.Reset()
+	var err error
+	if jsonAPI.bodyReader != nil {
+		var data []byte
+		data, err = jsonAPI.bodyReader(response)
+		buffer.Write(data)
+	} else {
+		_, err = buffer.ReadFrom(response.Body)
+	}
 	response.Body.Close()
-	return body, err
+	if err != nil {
+		releaseBody(buffer)
+		return nil, err
+	}
+	return buffer, nil
+}
+
+func releaseBody(buffer *bytes.Buffer) {
+	if buffer.Cap() <= bodyPoolMaxSize {
+		bodyPool.Put(buffer)
+	}
 }
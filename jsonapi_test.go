@@ -0,0 +1,505 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// failOnError returns error callbacks that fail the test
+func failOnError(t *testing.T) (HTTPErrorCallback, InternalErrorCallback) {
+	return func(statusCode int, statusMessage, errorMessage string) {
+			t.Errorf("HTTP error %d: %s", statusCode, statusMessage)
+		}, func(err error) {
+			t.Errorf("internal error: %v", err)
+		}
+}
+
+func TestDiscardBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("not json"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	succeeded := false
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Post("/", nil, map[string]int{"a": 1}, DiscardBody, func() {
+		succeeded = true
+	}, onHTTPError, onInternalError)
+	if !succeeded {
+		t.Fatal("request did not succeed")
+	}
+}
+
+func jsonServer(body string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+}
+
+func TestMaxJSONDepth(t *testing.T) {
+	server := jsonServer(`{"a":[[[1]]]}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetMaxJSONDepth(3)
+	var err error
+	var result interface{}
+	jsonAPI.Get("/", nil, &result, func() {}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if err != ErrMaxJSONDepth {
+		t.Fatalf("err = %v, want ErrMaxJSONDepth", err)
+	}
+
+	jsonAPI.SetMaxJSONDepth(4)
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, &result, func() {}, onHTTPError, onInternalError)
+}
+
+func TestProblemDetails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"type":"https://example.com/probs/out-of-credit",
+			"title":"You do not have enough credit.","status":403,
+			"detail":"Your current balance is 30, but that costs 50.",
+			"instance":"/account/12345/msgs/abc"}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	err := jsonAPI.Request().GetJSON("/", nil, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v, want an *HTTPError", err)
+	}
+	want := Error{
+		Error:    "You do not have enough credit.",
+		Status:   403,
+		Message:  "Your current balance is 30, but that costs 50.",
+		Type:     "https://example.com/probs/out-of-credit",
+		Instance: "/account/12345/msgs/abc",
+	}
+	if httpErr.Details != want {
+		t.Fatalf("details = %+v, want %+v", httpErr.Details, want)
+	}
+}
+
+func TestHostHeaders(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("X-A")+"|"+r.Header.Get("X-B"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{Headers: map[string]string{"X-A": "default", "X-B": "default"}}
+	jsonAPI.SetHostHeaders("127.0.0.1", map[string]string{"X-A": "host"})
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get(server.URL, nil, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.Get(strings.Replace(server.URL, "127.0.0.1", "localhost", 1), nil, nil,
+		func() {}, onHTTPError, onInternalError)
+	if len(received) != 2 || received[0] != "host|default" || received[1] != "default|default" {
+		t.Fatalf("received %q", received)
+	}
+}
+
+func TestResponseTee(t *testing.T) {
+	server := jsonServer(`{"a":1}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var tee bytes.Buffer
+	jsonAPI.SetResponseTee(&tee)
+	var result map[string]int
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, &result, func() {}, onHTTPError, onInternalError)
+	if tee.String() != `{"a":1}` || result["a"] != 1 {
+		t.Fatalf("tee = %q, result = %v", tee.String(), result)
+	}
+}
+
+func TestPooledBodies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"bad","error":"worse"}`))
+			return
+		}
+		w.Write([]byte(`{"raw":"` + strings.Repeat(r.URL.Path[1:], 1000) + `"}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	var results []map[string]json.RawMessage
+	for _, path := range []string{"/a", "/b", "/c"} {
+		var result map[string]json.RawMessage
+		jsonAPI.Get(path, nil, &result, func() {}, onHTTPError, onInternalError)
+		results = append(results, result)
+	}
+	var message string
+	jsonAPI.Get("/error", nil, nil, func() {}, func(statusCode int, statusMessage, errorMessage string) {
+		message = statusMessage
+	}, onInternalError)
+
+	for i, letter := range []string{"a", "b", "c"} {
+		if want := `"` + strings.Repeat(letter, 1000) + `"`; string(results[i]["raw"]) != want {
+			t.Errorf("result %d was overwritten by a later response", i)
+		}
+	}
+	if message != "bad" {
+		t.Fatalf("message = %q", message)
+	}
+}
+
+func slowServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+}
+
+func TestGetWithTimeout(t *testing.T) {
+	server := slowServer()
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var err error
+	jsonAPI.GetWithTimeout(20*time.Millisecond, "/", nil, nil, func() {
+		t.Error("slow request succeeded")
+	}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestErrorDecoders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"json message","error":"json error"}`))
+		case "/xml":
+			w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<e><message>xml message</message><error>xml error</error></e>`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"message":"not parsed"}`))
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.RegisterErrorDecoder("application/xml", XMLErrorDecoder)
+	tests := map[string]string{
+		"/json": "json message|json error",
+		"/xml":  "xml message|xml error",
+		"/text": `{"message":"not parsed"}|400 Bad Request`,
+	}
+	for path, want := range tests {
+		var got string
+		jsonAPI.Get(path, nil, nil, func() {}, func(statusCode int, statusMessage, errorMessage string) {
+			got = statusMessage + "|" + errorMessage
+		}, func(err error) {
+			t.Errorf("%s: %v", path, err)
+		})
+		if got != want {
+			t.Errorf("%s: got %q, want %q", path, got, want)
+		}
+	}
+}
+
+// onOff decodes "on" as 1 and null as -1
+type onOff int
+
+func (value *onOff) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "null":
+		*value = -1
+	case `"on"`:
+		*value = 1
+	}
+	return nil
+}
+
+func TestResponseBodyUnmarshaler(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/null" {
+			w.Write([]byte(`null`))
+			return
+		}
+		w.Write([]byte(`{"state":"on","name":null,"title":null}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	result := struct {
+		State onOff
+		Name  sql.NullString
+		Title *string
+	}{Title: new(string)}
+	jsonAPI.Get("/", nil, &result, func() {}, onHTTPError, onInternalError)
+	if result.State != 1 || result.Name.Valid || result.Title != nil {
+		t.Fatalf("result = %+v", result)
+	}
+
+	var value onOff
+	jsonAPI.Get("/null", nil, &value, func() {}, onHTTPError, onInternalError)
+	if value != -1 {
+		t.Fatalf("value = %d, want -1 from null", value)
+	}
+}
+
+func TestResponseBodyTargets(t *testing.T) {
+	server := jsonServer(`{"name":"x","count":2}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	var result struct {
+		Name  string
+		Count int
+	}
+	jsonAPI.Get("/", nil, &result, func() {}, onHTTPError, onInternalError)
+	if result.Name != "x" || result.Count != 2 {
+		t.Fatalf("result = %+v, want the struct filled in", result)
+	}
+
+	succeeded := false
+	jsonAPI.Get("/", nil, nil, func() {
+		succeeded = true
+	}, onHTTPError, onInternalError)
+	if !succeeded {
+		t.Fatal("request with a nil target did not succeed")
+	}
+}
+
+func TestClientTimeout(t *testing.T) {
+	server := slowServer()
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, Client: &http.Client{Timeout: time.Millisecond}}
+	var err error
+	jsonAPI.Get("/", nil, nil, func() {
+		t.Error("slow request succeeded")
+	}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if err == nil {
+		t.Fatal("request outlived the client timeout")
+	}
+
+	other := &JSONAPI{BaseURL: server.URL}
+	if other.httpClient().Timeout != 0 {
+		t.Fatal("client timeout leaked into another JSONAPI")
+	}
+}
+
+func TestDeadlineHeader(t *testing.T) {
+	var timeout string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout = r.Header.Get("X-Server-Timeout")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetDeadlineHeader("X-Server-Timeout")
+	jsonAPI.Request().Send()
+	if timeout != "" {
+		t.Fatalf("X-Server-Timeout = %q without a deadline", timeout)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	jsonAPI.Request().WithContext(ctx).Send()
+	if milliseconds, _ := strconv.Atoi(timeout); milliseconds < 1900 || milliseconds > 2000 {
+		t.Fatalf("X-Server-Timeout = %q, want the remaining milliseconds", timeout)
+	}
+}
+
+type nopPlugin struct{}
+
+func (nopPlugin) BeforeRequest(*http.Request) error { return nil }
+
+func (nopPlugin) AfterResponse(*http.Response) error { return nil }
+
+// TestConcurrentSetters is meant for go test -race, it changes everything
+// that may be changed while requests are in flight
+func TestConcurrentSetters(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				jsonAPI.Request().GetJSON("/", nil, nil)
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprint("X-Test-", i)
+		jsonAPI.SetHeader(name, "1")
+		jsonAPI.DelHeader(name)
+		jsonAPI.SetHostHeaders("127.0.0.1", map[string]string{name: "1"})
+		jsonAPI.RegisterErrorDecoder("application/xml", XMLErrorDecoder)
+		jsonAPI.PropagateContextHeader(name, name)
+		jsonAPI.AddPlugin(nopPlugin{})
+		jsonAPI.UseResponse(func(*http.Response) error { return nil })
+		time.Sleep(100 * time.Microsecond)
+	}
+	wg.Wait()
+}
+
+func TestErrorParser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"errors":[{"title":"Invalid","detail":"name is blank"},{"detail":"age too low"}]}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.ErrorParser = func(statusCode int, body []byte) (string, string) {
+		var document struct {
+			Errors []struct{ Title, Detail string }
+		}
+		json.Unmarshal(body, &document)
+		var details []string
+		for _, e := range document.Errors {
+			details = append(details, e.Detail)
+		}
+		return strings.Join(details, "; "), document.Errors[0].Title
+	}
+	var reported string
+	onHTTPError := func(statusCode int, statusMessage, errorMessage string) {
+		reported = fmt.Sprint(statusCode, "|", statusMessage, "|", errorMessage)
+	}
+	_, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, onInternalError)
+	if reported != "422|name is blank; age too low|Invalid" {
+		t.Fatalf("reported %q", reported)
+	}
+
+	jsonAPI.ErrorParser = func(int, []byte) (string, string) {
+		return "", ""
+	}
+	jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, onInternalError)
+	if !strings.HasSuffix(reported, "|422 Unprocessable Entity") {
+		t.Fatalf("reported %q, want the status as the error", reported)
+	}
+}
+
+func TestHeadAndOptions(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method == "HEAD" {
+			w.Header().Set("Content-Length", "7")
+		}
+		if r.Method == "OPTIONS" {
+			w.Write([]byte(`{"a":1}`))
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var seen string
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Head("/", nil, func(statusCode int, header http.Header) {
+		seen = fmt.Sprint(statusCode, " ", header.Get("X-Method"), " ", header.Get("Content-Length"))
+	}, onHTTPError, onInternalError)
+	if seen != "200 HEAD 7" {
+		t.Fatalf("Head saw %q", seen)
+	}
+
+	var result struct{ A int }
+	jsonAPI.Options("/", nil, &result, func(statusCode int, header http.Header) {
+		seen = header.Get("X-Method") + " " + header.Get("Allow")
+	}, onHTTPError, onInternalError)
+	if seen != "OPTIONS GET, HEAD, OPTIONS" || result.A != 1 {
+		t.Fatalf("Options saw %q, result = %+v", seen, result)
+	}
+}
+
+func TestBodyReader(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/error" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+		}
+		w.Write([]byte("0007" + `{"a":1}` + "0000"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetBodyReader(func(response *http.Response) ([]byte, error) {
+		raw, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			return nil, err
+		}
+		n, _ := strconv.Atoi(string(raw[:4]))
+		return raw[4 : 4+n], nil
+	})
+	var result struct{ A int }
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err != nil || result.A != 1 {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+	err := jsonAPI.Request().GetJSON("/error", nil, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Details.Message != `{"a":1}` {
+		t.Fatalf("err = %v, want the framed error body", err)
+	}
+}
+
+// BenchmarkGet measures a GET decoding a 64 KiB body, which is read into a
+// pooled buffer
+func BenchmarkGet(b *testing.B) {
+	body := []byte(`{"id":1,"data":"` + strings.Repeat("x", 64<<10) + `"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var response struct{ ID int }
+		jsonAPI.Get("/", nil, &response, func() {}, func(statusCode int, _, _ string) {
+			b.Fatal(statusCode)
+		}, func(err error) {
+			b.Fatal(err)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package jsonapi
+
+import "bytes"
+
+// AnyJSONPCallback makes SetStripJSONP strip wrappers with any callback name
+const AnyJSONPCallback = "*"
+
+// SetStripJSONP makes JSON responses wrapped in a JSONP callback, such as
+// callback({...});, be unwrapped before decoding, "" turns it off
+func (jsonAPI *JSONAPI) SetStripJSONP(callback string) {
+	jsonAPI.jsonpCallback = callback
+}
+
+// stripJSONP returns the argument of a JSONP call of callback, or body as it
+// is when it is not one
+func stripJSONP(body []byte, callback string) []byte {
+	open := bytes.IndexByte(body, '(')
+	if open <= 0 {
+		return body
+	}
+
+	name := bytes.TrimSpace(body[:open])
+	if callback == AnyJSONPCallback && !isJSONPCallback(name) ||
+		callback != AnyJSONPCallback && string(name) != callback {
+		return body
+	}
+
+	rest := bytes.TrimRight(body[open+1:], " \t\r\n")
+	rest = bytes.TrimSuffix(rest, []byte(";"))
+	rest = bytes.TrimRight(rest, " \t\r\n")
+	if len(rest) == 0 || rest[len(rest)-1] != ')' {
+		return body
+	}
+	return rest[:len(rest)-1]
+}
+
+func isJSONPCallback(name []byte) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '_' || c == '$' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,40 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStripJSONP(t *testing.T) {
+	body := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result struct{ A int }
+	body = "cb({\"a\":1});\n"
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err == nil {
+		t.Fatal("JSONP decoded without SetStripJSONP")
+	}
+	jsonAPI.SetStripJSONP("cb")
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err != nil || result.A != 1 {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+
+	body = `other({"a":2})`
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err == nil {
+		t.Fatal("another callback was stripped")
+	}
+	jsonAPI.SetStripJSONP(AnyJSONPCallback)
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err != nil || result.A != 2 {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+
+	body = `{"a":3}`
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err != nil || result.A != 3 {
+		t.Fatalf("plain JSON: err = %v, result = %+v", err, result)
+	}
+}
@@ -0,0 +1,39 @@
+package jsonapi
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SetAcceptLanguage sets the Accept-Language sent by requests that do not
+// set their own, see Request.SetAcceptLanguage
+func (jsonAPI *JSONAPI) SetAcceptLanguage(langs ...string) {
+	jsonAPI.acceptLanguage = acceptLanguage(langs)
+}
+
+// SetAcceptLanguage sets the Accept-Language header, langs are weighted
+// from most to least preferred
+func (r *Request) SetAcceptLanguage(langs ...string) *Request {
+	return r.SetHeader("Accept-Language", acceptLanguage(langs))
+}
+
+// acceptLanguage gives every language after the first a quality 0.1 lower
+// than the previous one, down to 0.1
+func acceptLanguage(langs []string) string {
+	var header strings.Builder
+	for i, lang := range langs {
+		if i > 0 {
+			tenths := 10 - i
+			if tenths < 1 {
+				tenths = 1
+			}
+			header.WriteString(", ")
+			header.WriteString(lang)
+			header.WriteString(";q=0.")
+			header.WriteString(strconv.Itoa(tenths))
+			continue
+		}
+		header.WriteString(lang)
+	}
+	return header.String()
+}
@@ -0,0 +1,35 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	var acceptLanguage string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptLanguage = r.Header.Get("Accept-Language")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if acceptLanguage != "" {
+		t.Fatalf("Accept-Language = %q by default", acceptLanguage)
+	}
+	jsonAPI.SetAcceptLanguage("nb")
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if acceptLanguage != "nb" {
+		t.Fatalf("Accept-Language = %q, want nb", acceptLanguage)
+	}
+	jsonAPI.Request().SetAcceptLanguage("en-US", "en", "fr").GetJSON("/", nil, nil)
+	if acceptLanguage != "en-US, en;q=0.9, fr;q=0.8" {
+		t.Fatalf("Accept-Language = %q, want descending weights", acceptLanguage)
+	}
+	jsonAPI.Request().SetAcceptLanguage("a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k").GetJSON("/", nil, nil)
+	if !strings.HasSuffix(acceptLanguage, "i;q=0.2, j;q=0.1, k;q=0.1") {
+		t.Fatalf("Accept-Language = %q, want weights to stop at 0.1", acceptLanguage)
+	}
+}
@@ -0,0 +1,74 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// ParamsOptions controls how ParamsWith formats values, zero fields use the
+// defaults of Params
+type ParamsOptions struct {
+	// TimeLayout formats time.Time values, time.RFC3339 by default
+	TimeLayout string
+	// FormatBool formats bool values, strconv.FormatBool by default
+	FormatBool func(bool) string
+}
+
+// Params converts a map of arbitrary values to url.Values, slices are added
+// as repeated keys and nil values are skipped
+func Params(m map[string]interface{}) url.Values {
+	return ParamsWith(m, ParamsOptions{})
+}
+
+// ParamsWith converts a map of arbitrary values to url.Values like Params,
+// formatting times and bools as options says
+func ParamsWith(m map[string]interface{}, options ParamsOptions) url.Values {
+	if options.TimeLayout == "" {
+		options.TimeLayout = time.RFC3339
+	}
+	if options.FormatBool == nil {
+		options.FormatBool = strconv.FormatBool
+	}
+
+	parameters := url.Values{}
+	for key, value := range m {
+		if value == nil {
+			continue
+		}
+
+		v := reflect.ValueOf(value)
+		if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) &&
+			v.Type().Elem().Kind() != reflect.Uint8 {
+			for i := 0; i < v.Len(); i++ {
+				parameters.Add(key, options.format(v.Index(i).Interface()))
+			}
+			continue
+		}
+
+		parameters.Add(key, options.format(value))
+	}
+
+	return parameters
+}
+
+func (options *ParamsOptions) format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		return options.FormatBool(v)
+	case time.Time:
+		return v.Format(options.TimeLayout)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
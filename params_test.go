@@ -0,0 +1,48 @@
+package jsonapi
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParams(t *testing.T) {
+	moment := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	parameters := Params(map[string]interface{}{
+		"int":   3,
+		"bool":  true,
+		"float": 1.5,
+		"time":  moment,
+		"slice": []int{1, 2},
+		"nil":   nil,
+	})
+	want := url.Values{
+		"int":   {"3"},
+		"bool":  {"true"},
+		"float": {"1.5"},
+		"time":  {"2020-01-02T03:04:05Z"},
+		"slice": {"1", "2"},
+	}
+	if !reflect.DeepEqual(parameters, want) {
+		t.Fatalf("parameters = %v, want %v", parameters, want)
+	}
+}
+
+func TestParamsWith(t *testing.T) {
+	moment := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	parameters := ParamsWith(map[string]interface{}{"bool": false, "time": moment},
+		ParamsOptions{
+			TimeLayout: "2006-01-02",
+			FormatBool: func(b bool) string {
+				if b {
+					return "1"
+				}
+				return "0"
+			},
+		})
+	want := url.Values{"bool": {"0"}, "time": {"2020-01-02"}}
+	if !reflect.DeepEqual(parameters, want) {
+		t.Fatalf("parameters = %v, want %v", parameters, want)
+	}
+}
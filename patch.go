@@ -0,0 +1,63 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+// PatchOp is a single RFC 6902 JSON Patch operation
+type PatchOp struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	From string `json:"from,omitempty"`
+	// Value is sent for add, replace and test operations even when it is
+	// nil, which sets or tests for null
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON leaves out value only for operations that take none
+func (op PatchOp) MarshalJSON() ([]byte, error) {
+	type patchOp PatchOp
+	switch op.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			patchOp
+			Value interface{} `json:"value"`
+		}{patchOp(op), op.Value})
+	}
+	return json.Marshal(patchOp(op))
+}
+
+// Patch request
+func (r *Request) Patch(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	r.executeVerb("PATCH", url, parameters, requestBody, responseBody, onSuccess,
+		onHTTPError, onInternalError)
+}
+
+// PatchBody request, sending requestBody like Patch and returning HTTP
+// errors as an *HTTPError, PatchJSON is the one for JSON Patch documents
+func (r *Request) PatchBody(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}) error {
+	return r.sendJSON("PATCH", url, parameters, requestBody, responseBody)
+}
+
+// PatchJSON request, sending ops as an application/json-patch+json document,
+// which is always JSON whatever codec is set
+func (r *Request) PatchJSON(url string, ops []PatchOp, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	document, err := json.Marshal(ops)
+	if err != nil {
+		onInternalError(err)
+		return
+	}
+
+	r.method = "PATCH"
+	r.path = url
+	r.requestBody = json.RawMessage(document)
+	r.responseBody = responseBody
+	r.contentType = "application/json-patch+json"
+	r.execute(onSuccess, onHTTPError, onInternalError)
+}
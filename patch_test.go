@@ -0,0 +1,138 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestPatchJSON(t *testing.T) {
+	var method, contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, contentType = r.Method, r.Header.Get("Content-Type")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().PatchJSON("/", []PatchOp{
+		{Op: "replace", Path: "/x", Value: 1},
+		{Op: "remove", Path: "/y"},
+	}, nil, func() {}, onHTTPError, onInternalError)
+	if method != "PATCH" || contentType != "application/json-patch+json" {
+		t.Fatalf("sent %s with Content-Type %q", method, contentType)
+	}
+	if body != `[{"op":"replace","path":"/x","value":1},{"op":"remove","path":"/y"}]` {
+		t.Fatalf("body = %s", body)
+	}
+}
+
+func TestPatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(map[string]string{"m": r.Method, "b": string(body), "q": r.URL.RawQuery})
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result struct{ M, B, Q string }
+	done := false
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Patch("/x", url.Values{"a": {"1"}}, map[string]int{"n": 1}, &result, func() {
+		done = true
+	}, onHTTPError, onInternalError)
+	if !done || result.M != "PATCH" || result.B != `{"n":1}` || result.Q != "a=1" {
+		t.Fatalf("done = %v, result = %+v", done, result)
+	}
+
+	result.M = ""
+	jsonAPI.Request().Patch("/x", nil, map[string]int{"n": 1}, &result, func() {}, onHTTPError, onInternalError)
+	if result.M != "PATCH" || result.B != `{"n":1}` {
+		t.Fatalf("Request().Patch: result = %+v", result)
+	}
+}
+
+func TestPatchOpValue(t *testing.T) {
+	ops := []PatchOp{
+		{Op: "replace", Path: "/a", Value: nil},
+		{Op: "remove", Path: "/b"},
+		{Op: "move", From: "/c", Path: "/d"},
+	}
+	document, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[{"op":"replace","path":"/a","value":null},{"op":"remove","path":"/b"},` +
+		`{"op":"move","path":"/d","from":"/c"}]`
+	if string(document) != want {
+		t.Fatalf("document = %s, want %s", document, want)
+	}
+}
+
+func TestPatchJSONIgnoresCodec(t *testing.T) {
+	var contentType, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetCodec(XMLCodec)
+	jsonAPI.Request().PatchJSON("/", []PatchOp{{Op: "add", Path: "/a", Value: 1}}, nil,
+		func() {}, func(statusCode int, _, _ string) {
+			t.Fatal(statusCode)
+		}, func(err error) {
+			t.Fatal(err)
+		})
+	if contentType != "application/json-patch+json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if body != `[{"op":"add","path":"/a","value":1}]` {
+		t.Errorf("body = %s", body)
+	}
+}
+
+func TestPatchBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "PATCH" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		data, _ := ioutil.ReadAll(r.Body)
+		w.Write(data)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result struct{ Name string }
+	err := jsonAPI.Request().PatchBody("/", nil, map[string]string{"name": "x"}, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Name != "x" {
+		t.Fatalf("name = %q, want x", result.Name)
+	}
+}
+
+func TestPatchJSONIgnoresHeadersContentType(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, Headers: map[string]string{"Content-Type": "application/json"}}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().PatchJSON("/", []PatchOp{{Op: "remove", Path: "/y"}}, nil, func() {},
+		onHTTPError, onInternalError)
+	if contentType != "application/json-patch+json" {
+		t.Fatalf("Content-Type = %q, want application/json-patch+json", contentType)
+	}
+}
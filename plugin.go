@@ -0,0 +1,34 @@
+package jsonapi
+
+import "net/http"
+
+// Plugin hooks into every request sent by a JSONAPI
+type Plugin interface {
+	// BeforeRequest runs right before the request is sent
+	BeforeRequest(request *http.Request) error
+	// AfterResponse runs right after the response is received, before its
+	// body is read
+	AfterResponse(response *http.Response) error
+}
+
+// AddPlugin registers plugins, they run in the order they were added
+func (jsonAPI *JSONAPI) AddPlugin(plugins ...Plugin) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	jsonAPI.plugins = append(jsonAPI.plugins[:len(jsonAPI.plugins):len(jsonAPI.plugins)],
+		plugins...)
+}
+
+// ResponseMiddleware runs on every response before it is passed to the
+// callbacks, an error is passed to the internal error callback instead
+type ResponseMiddleware func(response *http.Response) error
+
+// UseResponse registers response middleware, it runs after the plugins in
+// the order it was added
+func (jsonAPI *JSONAPI) UseResponse(middleware ...ResponseMiddleware) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	jsonAPI.responseMiddleware = append(
+		jsonAPI.responseMiddleware[:len(jsonAPI.responseMiddleware):len(jsonAPI.responseMiddleware)],
+		middleware...)
+}
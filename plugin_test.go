@@ -0,0 +1,95 @@
+package jsonapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type tracePlugin struct {
+	status int
+	fail   bool
+}
+
+func (plugin *tracePlugin) BeforeRequest(request *http.Request) error {
+	request.Header.Set("X-Trace", "t1")
+	return nil
+}
+
+func (plugin *tracePlugin) AfterResponse(response *http.Response) error {
+	plugin.status = response.StatusCode
+	if plugin.fail {
+		return errors.New("plugin failed")
+	}
+	return nil
+}
+
+func TestAddPlugin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Trace") != "t1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	plugin := &tracePlugin{}
+	jsonAPI.AddPlugin(plugin)
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, onInternalError)
+	if plugin.status != http.StatusCreated {
+		t.Fatalf("plugin saw %d, want 201", plugin.status)
+	}
+
+	plugin.fail = true
+	var err error
+	jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, func(internalErr error) {
+		err = internalErr
+	})
+	if err == nil || err.Error() != "plugin failed" {
+		t.Fatalf("err = %v, want the plugin error", err)
+	}
+}
+
+func TestUseResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/expired" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	errExpired := errors.New("expired")
+	var seen []int
+	jsonAPI.UseResponse(func(response *http.Response) error {
+		seen = append(seen, response.StatusCode)
+		return nil
+	}, func(response *http.Response) error {
+		if response.StatusCode == http.StatusUnauthorized {
+			return errExpired
+		}
+		return nil
+	})
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var err error
+	jsonAPI.Get("/expired", nil, nil, func() {
+		t.Error("expired request succeeded")
+	}, func(int, string, string) {
+		t.Error("middleware error reported as an HTTP error")
+	}, func(internalErr error) {
+		err = internalErr
+	})
+	if err != errExpired || !reflect.DeepEqual(seen, []int{200, 401}) {
+		t.Fatalf("err = %v, middleware saw %v", err, seen)
+	}
+}
@@ -0,0 +1,133 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrPollAttemptsExceeded is returned when PostAndPoll runs out of attempts
+var ErrPollAttemptsExceeded = errors.New("jsonapi: operation not done after maximum poll attempts")
+
+// ErrNoLocation is returned when a 202 Accepted response to PostAndPoll, or
+// the response to PostAndFetch, has no Location
+var ErrNoLocation = errors.New("jsonapi: response has no Location header")
+
+// PostAndPoll posts body and, when the response is 202 Accepted, polls its
+// Location every pollInterval, at most maxAttempts times, until isDone
+// reports the status as done, the final status, or a non 202 response, is
+// decoded into result, cancelling ctx stops polling
+func (jsonAPI *JSONAPI) PostAndPoll(ctx context.Context, url string, body interface{},
+	pollInterval time.Duration, maxAttempts int, isDone func(json.RawMessage) bool,
+	result interface{}) error {
+	var status json.RawMessage
+	response, err := jsonAPI.Request().WithContext(ctx).Method("POST").Path(url).
+		Body(body).Into(&status).Send()
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusAccepted {
+		return decodeResult(status, result)
+	}
+
+	location, err := response.Location()
+	if err == http.ErrNoLocation {
+		return ErrNoLocation
+	}
+	if err != nil {
+		return err
+	}
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-jsonAPI.after(pollInterval):
+		}
+		status = nil
+		poll := jsonAPI.Request().WithContext(ctx).Into(&status)
+		poll.absoluteURL = location.String()
+		_, err = poll.Send()
+		if err != nil {
+			return err
+		}
+
+		if isDone(status) {
+			return decodeResult(status, result)
+		}
+	}
+	return ErrPollAttemptsExceeded
+}
+
+func decodeResult(body json.RawMessage, result interface{}) error {
+	if result == nil || len(body) == 0 {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+// LongPoll polls url until stop is closed, every non empty response is
+// passed to onData and empty polls, or polls that take longer than timeout,
+// are sent again right away, HTTP errors and errors from onData end the loop
+func (jsonAPI *JSONAPI) LongPoll(url string, parameters url.Values, timeout time.Duration,
+	onData func(json.RawMessage) error, stop <-chan struct{}) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		var data json.RawMessage
+		poll := jsonAPI.newRequest("GET", url, parameters, nil, &data)
+		poll.ctx = ctx
+		poll.timeout = timeout
+		_, err := poll.Send()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(data) != 0 {
+			err = onData(data)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// PostAndFetch posts body and then gets the resource at the Location of the
+// response into result, relative locations are resolved against url
+func (jsonAPI *JSONAPI) PostAndFetch(url string, body interface{}, result interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	post := jsonAPI.newRequest("POST", url, nil, body, DiscardBody)
+	post.execute(func() {
+		location, err := post.response.Location()
+		if err == http.ErrNoLocation {
+			onInternalError(ErrNoLocation)
+			return
+		}
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+
+		fetch := jsonAPI.newRequest("GET", "", nil, nil, result)
+		fetch.absoluteURL = location.String()
+		fetch.execute(onSuccess, onHTTPError, onInternalError)
+	}, onHTTPError, onInternalError)
+}
@@ -0,0 +1,184 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func pollServer(polls *int, doneAfter int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Header().Set("Location", "/status")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		*polls++
+		if *polls < doneAfter {
+			w.Write([]byte(`{"done":false}`))
+			return
+		}
+		w.Write([]byte(`{"done":true,"value":7}`))
+	}))
+}
+
+func isDone(status json.RawMessage) bool {
+	var decoded struct{ Done bool }
+	json.Unmarshal(status, &decoded)
+	return decoded.Done
+}
+
+func TestPostAndPoll(t *testing.T) {
+	polls := 0
+	server := pollServer(&polls, 3)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	clock := &fakeClock{}
+	jsonAPI.SetClock(clock)
+	var result struct{ Value int }
+	err := jsonAPI.PostAndPoll(context.Background(), "/", nil, time.Hour, 10, isDone, &result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Value != 7 || polls != 3 {
+		t.Fatalf("value = %d after %d polls, want 7 after 3", result.Value, polls)
+	}
+	if len(clock.waits) != 3 || clock.waits[0] != time.Hour {
+		t.Fatalf("waits = %v, want 3 of 1h", clock.waits)
+	}
+}
+
+func TestPostAndPollAttempts(t *testing.T) {
+	polls := 0
+	server := pollServer(&polls, 10)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetClock(&fakeClock{})
+	err := jsonAPI.PostAndPoll(context.Background(), "/", nil, time.Hour, 2, isDone, nil)
+	if err != ErrPollAttemptsExceeded {
+		t.Fatalf("err = %v, want ErrPollAttemptsExceeded", err)
+	}
+	if polls != 2 {
+		t.Fatalf("polls = %d, want 2", polls)
+	}
+}
+
+// fakeClock fires every wait at once and records how long it was asked for
+type fakeClock struct {
+	mu    sync.Mutex
+	waits []time.Duration
+}
+
+func (clock *fakeClock) Now() time.Time {
+	return time.Unix(0, 0)
+}
+
+func (clock *fakeClock) After(d time.Duration) <-chan time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.waits = append(clock.waits, d)
+	fired := make(chan time.Time, 1)
+	fired <- time.Unix(0, 0)
+	return fired
+}
+
+func TestLongPoll(t *testing.T) {
+	var mu sync.Mutex
+	polls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		polls++
+		poll := polls
+		mu.Unlock()
+		switch poll {
+		case 1:
+			// outlasts the poll timeout
+			<-r.Context().Done()
+		case 2:
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"x":1}`))
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	stop := make(chan struct{})
+	var data []string
+	err := jsonAPI.LongPoll("/", nil, 50*time.Millisecond, func(message json.RawMessage) error {
+		data = append(data, string(message))
+		close(stop)
+		return nil
+	}, stop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 1 || data[0] != `{"x":1}` {
+		t.Fatalf("data = %q, want one {\"x\":1}", data)
+	}
+}
+
+func TestPostAndFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST" && r.URL.Path == "/v1/items":
+			w.Header().Set("Location", "items/5")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id":5}`))
+		case r.Method == "POST":
+			w.WriteHeader(http.StatusCreated)
+		case r.URL.Path == "/v1/items/5":
+			w.Write([]byte(`{"id":5,"name":"new"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL + "/v1"}
+	var result struct {
+		ID   int
+		Name string
+	}
+	done := false
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.PostAndFetch("/items", map[string]string{"name": "new"}, &result, func() {
+		done = true
+	}, onHTTPError, onInternalError)
+	if !done || result.ID != 5 || result.Name != "new" {
+		t.Fatalf("done = %v, result = %+v, want the fetched Location", done, result)
+	}
+
+	var err error
+	jsonAPI.PostAndFetch("/other", nil, &result, func() {
+		t.Error("fetched without a Location")
+	}, onHTTPError, func(internalErr error) {
+		err = internalErr
+	})
+	if err != ErrNoLocation {
+		t.Fatalf("err = %v, want ErrNoLocation", err)
+	}
+}
+
+func TestPostAndPollCancel(t *testing.T) {
+	polls := 0
+	server := pollServer(&polls, 3)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := jsonAPI.PostAndPoll(ctx, "/", nil, time.Hour, 10, isDone, nil)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if polls != 0 {
+		t.Fatalf("polls = %d, want 0", polls)
+	}
+}
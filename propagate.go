@@ -0,0 +1,39 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+)
+
+type contextHeader struct {
+	name string
+	key  interface{}
+}
+
+// PropagateContextHeader makes requests send the value stored under key in
+// their context as the header name, requests without the value don't get it
+func (jsonAPI *JSONAPI) PropagateContextHeader(name string, key interface{}) {
+	jsonAPI.mu.Lock()
+	defer jsonAPI.mu.Unlock()
+	jsonAPI.contextHeaders = append(
+		jsonAPI.contextHeaders[:len(jsonAPI.contextHeaders):len(jsonAPI.contextHeaders)],
+		contextHeader{name, key})
+}
+
+func (jsonAPI *JSONAPI) setContextHeaders(request *http.Request) {
+	jsonAPI.mu.RLock()
+	contextHeaders := jsonAPI.contextHeaders
+	jsonAPI.mu.RUnlock()
+	ctx := request.Context()
+	for _, header := range contextHeaders {
+		value := ctx.Value(header.key)
+		if value == nil {
+			continue
+		}
+		if text, ok := value.(string); ok {
+			request.Header.Set(header.name, text)
+			continue
+		}
+		request.Header.Set(header.name, fmt.Sprint(value))
+	}
+}
@@ -0,0 +1,35 @@
+package jsonapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type traceKey struct{}
+
+func TestPropagateContextHeader(t *testing.T) {
+	var values []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		values = r.Header.Values("X-Trace-Id")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.PropagateContextHeader("X-Trace-Id", traceKey{})
+	ctx := context.WithValue(context.Background(), traceKey{}, "t-123")
+	jsonAPI.Request().WithContext(ctx).GetJSON("/", nil, nil)
+	if !reflect.DeepEqual(values, []string{"t-123"}) {
+		t.Fatalf("X-Trace-Id = %q, want the context value", values)
+	}
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if len(values) != 0 {
+		t.Fatalf("X-Trace-Id = %q without a context value", values)
+	}
+	jsonAPI.Request().WithContext(context.WithValue(ctx, traceKey{}, 42)).GetJSON("/", nil, nil)
+	if !reflect.DeepEqual(values, []string{"42"}) {
+		t.Fatalf("X-Trace-Id = %q, want the formatted value", values)
+	}
+}
@@ -0,0 +1,181 @@
+package jsonapi
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// Recorder intercepts requests to replay recorded responses instead of
+// sending them, and records the responses of requests that are sent
+type Recorder interface {
+	// Replay returns the recorded response to request, or nil to send it
+	Replay(request *http.Request) (*http.Response, error)
+	// Record saves the response to a sent request, body is its full body
+	Record(request *http.Request, response *http.Response, body []byte) error
+}
+
+// SetRecorder sets the recorder requests go through, nil sends every
+// request
+func (jsonAPI *JSONAPI) SetRecorder(recorder Recorder) {
+	jsonAPI.recorder = recorder
+}
+
+// roundTrip sends request, through the recorder when one is set
+func (jsonAPI *JSONAPI) roundTrip(request *http.Request) (*http.Response, error) {
+	if jsonAPI.recorder == nil {
+		return jsonAPI.transmit(request)
+	}
+
+	response, err := jsonAPI.recorder.Replay(request)
+	if err != nil || response != nil {
+		return response, err
+	}
+
+	response, err = jsonAPI.transmit(request)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	err = jsonAPI.recorder.Record(request, response, body)
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (jsonAPI *JSONAPI) transmit(request *http.Request) (*http.Response, error) {
+	if request.Method == "GET" && jsonAPI.hedgingDelay > 0 && jsonAPI.hedgingMaxExtra > 0 &&
+		hedgeable(request) {
+		return jsonAPI.doHedged(request)
+	}
+	return jsonAPI.httpClient().Do(request)
+}
+
+// RecorderMode controls when a FileRecorder sends requests
+type RecorderMode int
+
+const (
+	// ReplayOrRecord replays recorded responses and records the others
+	ReplayOrRecord RecorderMode = iota
+	// ReplayOnly replays recorded responses and fails any other request
+	ReplayOnly
+	// RecordOnly sends every request and records its response
+	RecordOnly
+)
+
+// ErrNoRecording is returned by a ReplayOnly FileRecorder for requests that
+// were never recorded
+var ErrNoRecording = errors.New("jsonapi: no recorded response for request")
+
+// FileRecorder records responses as JSON files in Dir, one per request
+// method, URL and body
+type FileRecorder struct {
+	Dir  string
+	Mode RecorderMode
+}
+
+type recording struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// Replay returns the response recorded in Dir for request
+func (recorder *FileRecorder) Replay(request *http.Request) (*http.Response, error) {
+	if recorder.Mode == RecordOnly {
+		return nil, nil
+	}
+
+	path, err := recorder.path(request)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) && recorder.Mode == ReplayOrRecord {
+		return nil, nil
+	}
+	if os.IsNotExist(err) {
+		return nil, ErrNoRecording
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var recording recording
+	err = json.Unmarshal(data, &recording)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status: fmt.Sprintf("%d %s", recording.StatusCode,
+			http.StatusText(recording.StatusCode)),
+		StatusCode:    recording.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recording.Header,
+		Body:          ioutil.NopCloser(bytes.NewReader(recording.Body)),
+		ContentLength: int64(len(recording.Body)),
+		Request:       request,
+	}, nil
+}
+
+// Record writes the response to a file in Dir
+func (recorder *FileRecorder) Record(request *http.Request, response *http.Response,
+	body []byte) error {
+	path, err := recorder.path(request)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(recording{
+		StatusCode: response.StatusCode,
+		Header:     response.Header,
+		Body:       body,
+	}, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(recorder.Dir, 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (recorder *FileRecorder) path(request *http.Request) (string, error) {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "%s %s\n", request.Method, request.URL)
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err != nil {
+			return "", err
+		}
+
+		_, err = io.Copy(hash, body)
+		body.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(recorder.Dir, hex.EncodeToString(hash.Sum(nil))+".json"), nil
+}
@@ -0,0 +1,39 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFileRecorder(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("X-H", "v")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"n":1}`))
+	}))
+	dir := t.TempDir()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetRecorder(&FileRecorder{Dir: dir, Mode: RecordOnly})
+	var result map[string]int
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Post("/", nil, 1, &result, func() {}, onHTTPError, onInternalError)
+	server.Close()
+
+	jsonAPI.SetRecorder(&FileRecorder{Dir: dir, Mode: ReplayOnly})
+	result = nil
+	response, err := jsonAPI.Request().Method("POST").Path("/").Body(1).Into(&result).Send()
+	if err != nil || hits != 1 || result["n"] != 1 {
+		t.Fatalf("err = %v after %d requests, result = %v", err, hits, result)
+	}
+	if response.StatusCode != http.StatusCreated || response.Header.Get("X-H") != "v" {
+		t.Fatalf("replayed %d with headers %v", response.StatusCode, response.Header)
+	}
+
+	if _, err := jsonAPI.Request().Method("POST").Path("/").Body(2).Send(); err != ErrNoRecording {
+		t.Fatalf("err = %v, want ErrNoRecording for another body", err)
+	}
+}
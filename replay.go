@@ -0,0 +1,62 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrNothingToReplay is returned by Replay for requests that were never sent
+var ErrNothingToReplay = errors.New("jsonapi: request has not been sent yet")
+
+// Replay sends the last request again with the same method, URL, headers
+// and body, and handles the response like Send, io.Reader bodies are kept
+// in memory while they are sent so that they can be replayed
+func (r *Request) Replay() (*http.Response, error) {
+	if r.sent == nil {
+		return nil, ErrNothingToReplay
+	}
+
+	r.replaying = true
+	defer func() {
+		r.replaying = false
+	}()
+	return r.Send()
+}
+
+// capture keeps a copy of request so it can be replayed, taken before the
+// client adds cookies to it, copying a body that can only be read once as it
+// is sent
+func (r *Request) capture(request *http.Request) {
+	r.sent = request.Clone(request.Context())
+	r.sentBody = request.GetBody
+	if r.sentBody != nil || request.Body == nil || request.Body == http.NoBody {
+		return
+	}
+
+	var body bytes.Buffer
+	request.Body = teeReadCloser{
+		Reader: io.TeeReader(request.Body, &body),
+		Closer: request.Body,
+	}
+	r.sentBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body.Bytes())), nil
+	}
+}
+
+// resend sends a copy of the captured request
+func (r *Request) resend(ctx context.Context) (*http.Response, error) {
+	request := r.sent.Clone(ctx)
+	if r.sentBody != nil {
+		body, err := r.sentBody()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = body
+		request.GetBody = r.sentBody
+	}
+	return r.do(request, nil, http.Header{})
+}
@@ -0,0 +1,70 @@
+package jsonapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReplay(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path+" "+r.Header.Get("X-Test")+" "+string(body))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	request := jsonAPI.Request().Method("PUT").Path("/things").
+		SetHeader("X-Test", "1").Body(strings.NewReader("abc"))
+	if _, err := request.Send(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := request.Replay(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, got := range requests {
+		if got != "PUT /things 1 abc" {
+			t.Errorf("request %d = %q, want PUT /things 1 abc", i, got)
+		}
+	}
+	if len(requests) != 3 {
+		t.Fatalf("sent %d requests, want 3", len(requests))
+	}
+}
+
+func TestReplayBeforeSend(t *testing.T) {
+	_, err := (&JSONAPI{}).Request().Replay()
+	if err != ErrNothingToReplay {
+		t.Fatalf("err = %v, want ErrNothingToReplay", err)
+	}
+}
+
+func TestReplayWithCookies(t *testing.T) {
+	var cookie string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cookie = r.Header.Get("Cookie")
+		http.SetCookie(w, &http.Cookie{Name: "s", Value: "x"})
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.EnableCookies()
+	request := jsonAPI.Request().Method("GET").Path("/")
+	if _, err := request.Send(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := request.Replay(); err != nil {
+			t.Fatal(err)
+		}
+		if cookie != "s=x" {
+			t.Fatalf("replay %d sent Cookie %q, want s=x", i, cookie)
+		}
+	}
+}
@@ -0,0 +1,987 @@
+package jsonapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Request is built with chained calls and sent with Send
+type Request struct {
+	api          *JSONAPI
+	method       string
+	path         string
+	absoluteURL  string
+	parameters   url.Values
+	query        []queryParam
+	orderedQuery bool
+	header       http.Header
+	requestBody  interface{}
+	rawBody      []byte
+	responseBody interface{}
+	onError      HTTPErrorCallback
+	onSuccess    SuccessCallbackV2
+	onRetry      RetryCallback
+	codec        Codec
+	contentType  string
+	response     *http.Response
+	errorDetails Error
+	peekSize     int
+	peekHook     PeekCallback
+	timeout      time.Duration
+	maxBytes     int64
+	forceClose   bool
+	err          error
+	expectStatus []int
+	id           string
+	ctx          context.Context
+	transport    http.RoundTripper
+	sent         *http.Request
+	sentBody     func() (io.ReadCloser, error)
+	replaying    bool
+	pathParams   map[string]string
+}
+
+// ErrUnexpectedBody is returned for GET and HEAD requests with a body when
+// SetStrictMethodBodies is enabled
+var ErrUnexpectedBody = errors.New("jsonapi: request body given for a method without one")
+
+// ErrResponseTooLarge is returned when a response body is larger than the
+// limit set with SetMaxResponseBytes
+var ErrResponseTooLarge = errors.New("jsonapi: response body too large")
+
+// PeekCallback runs with the first bytes of a response body before it is
+// decoded
+type PeekCallback func(prefix []byte) error
+
+// Request starts building a GET request to the base URL
+func (jsonAPI *JSONAPI) Request() *Request {
+	return &Request{
+		api:    jsonAPI,
+		method: "GET",
+		header: http.Header{},
+	}
+}
+
+func (jsonAPI *JSONAPI) newRequest(verb, url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}) *Request {
+	return &Request{
+		api:          jsonAPI,
+		method:       verb,
+		path:         url,
+		parameters:   parameters,
+		header:       http.Header{},
+		requestBody:  requestBody,
+		responseBody: responseBody,
+	}
+}
+
+// WithContext sets the context the request is sent with, cancelling it
+// aborts the request including reading the response body
+func (r *Request) WithContext(ctx context.Context) *Request {
+	r.ctx = ctx
+	return r
+}
+
+// WithValue adds a value to the context the request is sent with, plugins
+// and response middleware find it in the context of the *http.Request and
+// callbacks read it with Value, WithContext replaces values set before it
+func (r *Request) WithValue(key, value interface{}) *Request {
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	r.ctx = context.WithValue(ctx, key, value)
+	return r
+}
+
+// Value returns the value added with WithValue, or found in the context set
+// with WithContext, for key
+func (r *Request) Value(key interface{}) interface{} {
+	if r.ctx == nil {
+		return nil
+	}
+	return r.ctx.Value(key)
+}
+
+// Timeout fails the request with an error wrapping
+// context.DeadlineExceeded when it, including reading the response body,
+// takes longer than d, the JSONAPI client is left unchanged
+func (r *Request) Timeout(d time.Duration) *Request {
+	r.timeout = d
+	return r
+}
+
+// Method sets the HTTP method
+func (r *Request) Method(method string) *Request {
+	r.method = method
+	return r
+}
+
+// Path sets the path, relative to the base URL
+func (r *Request) Path(path string) *Request {
+	r.path = path
+	return r
+}
+
+// PathParams sets the path from template, replacing every {name} placeholder
+// with the escaped value of params[name], a missing value fails the request
+func (r *Request) PathParams(template string, params map[string]string) *Request {
+	path, err := expandPath(template, params)
+	if err != nil {
+		r.err = err
+		return r
+	}
+
+	r.path = path
+	return r
+}
+
+// PathParam replaces the {name} placeholder in the path, set before or
+// after, with the escaped value once the request is sent
+func (r *Request) PathParam(name, value string) *Request {
+	if r.pathParams == nil {
+		r.pathParams = map[string]string{}
+	}
+	r.pathParams[name] = value
+	return r
+}
+
+// SetHeader sets a header, replacing any value from the JSONAPI's Headers
+func (r *Request) SetHeader(name, value string) *Request {
+	r.header.Set(name, value)
+	return r
+}
+
+// SetMaxForwards sets how many proxies may forward a TRACE or OPTIONS
+// request, which is sent with Method
+func (r *Request) SetMaxForwards(n int) *Request {
+	return r.SetHeader("Max-Forwards", strconv.Itoa(n))
+}
+
+// SetPrefer sets the Prefer header to preferences, such as return=minimal
+// or respond-async
+func (r *Request) SetPrefer(preferences ...string) *Request {
+	return r.SetHeader("Prefer", strings.Join(preferences, ", "))
+}
+
+// PreferenceApplied returns the Preference-Applied header of the response,
+// once the request has been sent
+func (r *Request) PreferenceApplied() string {
+	if r.response == nil {
+		return ""
+	}
+	return r.response.Header.Get("Preference-Applied")
+}
+
+// Query adds a query parameter
+func (r *Request) Query(key, value string) *Request {
+	r.query = append(r.query, queryParam{key, value})
+	return r
+}
+
+// QueryInt adds an integer query parameter
+func (r *Request) QueryInt(key string, value int) *Request {
+	return r.Query(key, strconv.Itoa(value))
+}
+
+// OrderedQuery makes parameters added with Query be encoded in the order
+// they were added, ahead of any other parameters, instead of sorted by key
+func (r *Request) OrderedQuery() *Request {
+	r.orderedQuery = true
+	return r
+}
+
+// Body sets the value serialized as the request body, []byte and
+// json.RawMessage values are sent as they are and io.Reader values are
+// streamed without a Content-Length, only serialized values and
+// json.RawMessage get a default Content-Type
+func (r *Request) Body(requestBody interface{}) *Request {
+	r.requestBody = requestBody
+	return r
+}
+
+// Into sets the value the response body is decoded into
+func (r *Request) Into(responseBody interface{}) *Request {
+	r.responseBody = responseBody
+	return r
+}
+
+// OnSuccess sets a callback that runs with the status code and headers of
+// a successful response once its body is decoded, before any success
+// callback passed to the verb
+func (r *Request) OnSuccess(onSuccess SuccessCallbackV2) *Request {
+	r.onSuccess = onSuccess
+	return r
+}
+
+// OnError sets a callback that runs on an errored HTTP request
+func (r *Request) OnError(onError HTTPErrorCallback) *Request {
+	r.onError = onError
+	return r
+}
+
+// PeekBytes makes hook run with up to the first n bytes of the response body
+// before it is decoded, the hook may change the decode target with Into
+func (r *Request) PeekBytes(n int, hook PeekCallback) *Request {
+	r.peekSize = n
+	r.peekHook = hook
+	return r
+}
+
+// SetMaxResponseBytes limits the size of the response body, 0 means
+// unlimited which is the default
+func (r *Request) SetMaxResponseBytes(n int64) *Request {
+	r.maxBytes = n
+	return r
+}
+
+// ForceClose makes the request send Connection: close and close the
+// connection after the response instead of keeping it alive
+func (r *Request) ForceClose() *Request {
+	r.forceClose = true
+	return r
+}
+
+// ExpectStatus makes any status other than codes an HTTP error, including
+// other 2xx statuses
+func (r *Request) ExpectStatus(codes ...int) *Request {
+	r.expectStatus = codes
+	return r
+}
+
+// SetTransport sends the request through rt, bypassing the JSONAPI client,
+// its transport settings, hedging and recorder
+func (r *Request) SetTransport(rt http.RoundTripper) *Request {
+	r.transport = rt
+	return r
+}
+
+// Send sends the request, HTTP errors are returned as an *HTTPError, or a
+// *RateLimitError for 429, alongside the response
+func (r *Request) Send() (*http.Response, error) {
+	var err error
+	onHTTPError, onInternalError := r.errorCallbacks(&err)
+	r.execute(func() {}, onHTTPError, onInternalError)
+	return r.response, err
+}
+
+// errorCallbacks returns callbacks storing errors in err, HTTP errors as an
+// *HTTPError and 429s as a *RateLimitError
+func (r *Request) errorCallbacks(err *error) (HTTPErrorCallback, InternalErrorCallback) {
+	onHTTPError := func(statusCode int, statusMessage, errorMessage string) {
+		if r.onError != nil {
+			r.onError(statusCode, statusMessage, errorMessage)
+		}
+		httpErr := &HTTPError{Details: Error{Error: errorMessage, Status: statusCode,
+			Message: statusMessage, Type: r.errorDetails.Type,
+			Instance: r.errorDetails.Instance}, Err: r.api.statusErrors[statusCode]}
+		*err = httpErr
+		if statusCode == http.StatusTooManyRequests {
+			retryAfter, _ := r.api.retryAfter(r.response)
+			*err = &RateLimitError{HTTPError: httpErr, RetryAfter: retryAfter}
+		}
+	}
+	onInternalError := func(internalErr error) {
+		*err = internalErr
+	}
+	return onHTTPError, onInternalError
+}
+
+// GetJSON request, returning HTTP errors as an *HTTPError
+func (r *Request) GetJSON(url string, parameters url.Values,
+	responseBody interface{}) error {
+	return r.sendJSON("GET", url, parameters, nil, responseBody)
+}
+
+// PutJSON request, returning HTTP errors as an *HTTPError
+func (r *Request) PutJSON(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}) error {
+	return r.sendJSON("PUT", url, parameters, requestBody, responseBody)
+}
+
+// PostJSON request, returning HTTP errors as an *HTTPError
+func (r *Request) PostJSON(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}) error {
+	return r.sendJSON("POST", url, parameters, requestBody, responseBody)
+}
+
+// DeleteJSON request, returning HTTP errors as an *HTTPError
+func (r *Request) DeleteJSON(url string, parameters url.Values,
+	responseBody interface{}) error {
+	return r.sendJSON("DELETE", url, parameters, nil, responseBody)
+}
+
+// Get request, parameters are merged with those added with Query
+func (r *Request) Get(url string, parameters url.Values, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	r.executeVerb("GET", url, parameters, nil, responseBody, onSuccess, onHTTPError,
+		onInternalError)
+}
+
+// Put request, parameters are merged with those added with Query
+func (r *Request) Put(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	r.executeVerb("PUT", url, parameters, requestBody, responseBody, onSuccess,
+		onHTTPError, onInternalError)
+}
+
+// Post request, parameters are merged with those added with Query
+func (r *Request) Post(url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	r.executeVerb("POST", url, parameters, requestBody, responseBody, onSuccess,
+		onHTTPError, onInternalError)
+}
+
+// Delete request, parameters are merged with those added with Query
+func (r *Request) Delete(url string, parameters url.Values, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	r.executeVerb("DELETE", url, parameters, nil, responseBody, onSuccess,
+		onHTTPError, onInternalError)
+}
+
+func (r *Request) executeVerb(verb, url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	r.method = verb
+	r.path = url
+	r.parameters = parameters
+	r.requestBody = requestBody
+	r.responseBody = responseBody
+	r.execute(onSuccess, onHTTPError, onInternalError)
+}
+
+// Head request, onSuccess gets the status code and headers as there is no
+// body
+func (r *Request) Head(url string, parameters url.Values,
+	onSuccess SuccessCallbackV2, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	r.executeWithHeaders("HEAD", url, parameters, nil, onSuccess, onHTTPError,
+		onInternalError)
+}
+
+// Options request, onSuccess gets the status code and headers such as Allow
+func (r *Request) Options(url string, parameters url.Values,
+	responseBody interface{}, onSuccess SuccessCallbackV2,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	r.executeWithHeaders("OPTIONS", url, parameters, responseBody, onSuccess,
+		onHTTPError, onInternalError)
+}
+
+func (r *Request) executeWithHeaders(verb, url string, parameters url.Values,
+	responseBody interface{}, onSuccess SuccessCallbackV2,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	r.method = verb
+	r.path = url
+	r.parameters = parameters
+	r.responseBody = responseBody
+	r.execute(func() {
+		onSuccess(r.response.StatusCode, r.response.Header)
+	}, onHTTPError, onInternalError)
+}
+
+// HeadResponse request, returning the response for its status code and
+// headers, and HTTP errors as an *HTTPError
+func (r *Request) HeadResponse(url string, parameters url.Values) (*http.Response, error) {
+	return r.sendVerb("HEAD", url, parameters, nil, nil)
+}
+
+// OptionsResponse request, returning the response for headers such as Allow,
+// and HTTP errors as an *HTTPError
+func (r *Request) OptionsResponse(url string, parameters url.Values,
+	responseBody interface{}) (*http.Response, error) {
+	return r.sendVerb("OPTIONS", url, parameters, nil, responseBody)
+}
+
+func (r *Request) sendJSON(verb, url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}) error {
+	_, err := r.sendVerb(verb, url, parameters, requestBody, responseBody)
+	return err
+}
+
+func (r *Request) sendVerb(verb, url string, parameters url.Values,
+	requestBody interface{}, responseBody interface{}) (*http.Response, error) {
+	r.method = verb
+	r.path = url
+	r.parameters = parameters
+	r.requestBody = requestBody
+	r.responseBody = responseBody
+	return r.Send()
+}
+
+func (r *Request) execute(onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	response, cancel := r.open(onHTTPError, onInternalError)
+	if response == nil {
+		return
+	}
+
+	defer cancel()
+	if r.onSuccess != nil {
+		then := onSuccess
+		onSuccess = func() {
+			r.onSuccess(response.StatusCode, response.Header)
+			then()
+		}
+	}
+	// redirects that were not followed and 304 Not Modified succeed without
+	// a body to decode, callbacks can tell them apart by the status code
+	responseBody := r.responseBody
+	if isRedirect(response.StatusCode) {
+		responseBody = DiscardBody
+	}
+	r.api.handleSuccess(response, responseBody, r.bodyCodec(), onSuccess,
+		onInternalError)
+}
+
+// isRedirect reports whether a successful status is a redirect that was not
+// followed or a 304 Not Modified, which have no content to read
+func isRedirect(statusCode int) bool {
+	return statusCode >= 300 && statusCode < 400
+}
+
+// open sends the request and returns a successful response with its body
+// unread, or nil when the outcome was already passed to a callback, cancel
+// must be called once the body has been read
+func (r *Request) open(onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) (*http.Response, context.CancelFunc) {
+	if r.err != nil {
+		onInternalError(r.err)
+		return nil, nil
+	}
+	err := r.checkMethodBody()
+	if err != nil {
+		onInternalError(err)
+		return nil, nil
+	}
+	if r.api.requestValidator != nil && r.requestBody != nil {
+		err = r.api.requestValidator(r.requestBody)
+		if err != nil {
+			onInternalError(err)
+			return nil, nil
+		}
+	}
+
+	ctx := r.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if ctx.Err() != nil {
+		onInternalError(ctx.Err())
+		return nil, nil
+	}
+
+	var cancel context.CancelFunc
+	if r.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, r.timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	response, err := r.sendWithRetries(ctx)
+	if err != nil {
+		cancel()
+		onInternalError(err)
+		return nil, nil
+	}
+
+	if r.maxBytes > 0 {
+		response.Body = &limitedBody{ReadCloser: response.Body, remaining: r.maxBytes}
+	}
+
+	if r.peekHook != nil {
+		err = r.peek(response)
+		if err != nil {
+			response.Body.Close()
+			cancel()
+			onInternalError(err)
+			return nil, nil
+		}
+	}
+
+	if r.isHTTPError(response.StatusCode) {
+		r.api.handleHTTPError(response, &r.errorDetails, onHTTPError, onInternalError)
+		cancel()
+		return nil, nil
+	}
+	return response, cancel
+}
+
+// checkMethodBody fails GET and HEAD requests with a body in strict mode
+// and otherwise reports them to OnUnexpectedBody
+func (r *Request) checkMethodBody() error {
+	if r.method != "GET" && r.method != "HEAD" ||
+		r.requestBody == nil && r.rawBody == nil {
+		return nil
+	}
+	if r.api.strictMethodBodies {
+		return ErrUnexpectedBody
+	}
+	if r.api.onUnexpectedBody != nil {
+		r.api.onUnexpectedBody(r.method, r.path)
+	}
+	return nil
+}
+
+func (r *Request) isHTTPError(statusCode int) bool {
+	if len(r.expectStatus) == 0 {
+		return statusCode >= 400
+	}
+
+	for _, code := range r.expectStatus {
+		if code == statusCode {
+			return false
+		}
+	}
+	return true
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (body *limitedBody) Read(p []byte) (int, error) {
+	if body.remaining < 0 {
+		return 0, ErrResponseTooLarge
+	}
+	if int64(len(p)) > body.remaining+1 {
+		p = p[:body.remaining+1]
+	}
+
+	n, err := body.ReadCloser.Read(p)
+	body.remaining -= int64(n)
+	if body.remaining < 0 {
+		return n + int(body.remaining), ErrResponseTooLarge
+	}
+	return n, err
+}
+
+func (r *Request) peek(response *http.Response) error {
+	reader := bufio.NewReaderSize(response.Body, r.peekSize)
+	prefix, err := reader.Peek(r.peekSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return err
+	}
+
+	response.Body = teeReadCloser{Reader: reader, Closer: response.Body}
+	return r.peekHook(prefix)
+}
+
+func expandPath(template string, params map[string]string) (string, error) {
+	var path strings.Builder
+	for {
+		start := strings.Index(template, "{")
+		if start == -1 {
+			break
+		}
+		end := strings.Index(template[start:], "}")
+		if end == -1 {
+			return "", fmt.Errorf("jsonapi: unterminated placeholder in path %q", template)
+		}
+
+		name := template[start+1 : start+end]
+		value, ok := params[name]
+		if !ok {
+			return "", fmt.Errorf("jsonapi: missing path parameter %q", name)
+		}
+
+		path.WriteString(template[:start])
+		path.WriteString(url.PathEscape(value))
+		template = template[start+end+1:]
+	}
+
+	path.WriteString(template)
+	return path.String(), nil
+}
+
+type queryParam struct {
+	key   string
+	value string
+}
+
+// encodeQuery merges the parameters with those added with Query
+func (r *Request) encodeQuery() string {
+	parameters, params := r.parameters, r.query
+	if r.api.dropEmptyParams {
+		parameters, params = dropEmptyParams(parameters, params)
+	}
+
+	if r.orderedQuery {
+		var query strings.Builder
+		for _, param := range params {
+			if query.Len() > 0 {
+				query.WriteByte('&')
+			}
+			query.WriteString(url.QueryEscape(param.key))
+			query.WriteByte('=')
+			query.WriteString(url.QueryEscape(param.value))
+		}
+		if len(parameters) != 0 {
+			if query.Len() > 0 {
+				query.WriteByte('&')
+			}
+			query.WriteString(parameters.Encode())
+		}
+		return query.String()
+	}
+
+	if len(params) == 0 {
+		return parameters.Encode()
+	}
+
+	merged := url.Values{}
+	for key, values := range parameters {
+		merged[key] = append([]string(nil), values...)
+	}
+	for _, param := range params {
+		merged.Add(param.key, param.value)
+	}
+	return merged.Encode()
+}
+
+// dropEmptyParams leaves out keys that only have empty values
+func dropEmptyParams(parameters url.Values, params []queryParam) (url.Values, []queryParam) {
+	kept := url.Values{}
+	for key, values := range parameters {
+		for _, value := range values {
+			if value != "" {
+				kept[key] = values
+				break
+			}
+		}
+	}
+
+	var keptParams []queryParam
+	for _, param := range params {
+		if param.value != "" {
+			keptParams = append(keptParams, param)
+		}
+	}
+	return kept, keptParams
+}
+
+func (r *Request) sendsParamsAsForm() bool {
+	if !r.api.paramsAsForm || (len(r.parameters) == 0 && len(r.query) == 0) {
+		return false
+	}
+	if r.requestBody != nil || r.rawBody != nil {
+		return false
+	}
+	return r.method == "POST" || r.method == "PUT" || r.method == "PATCH"
+}
+
+func (r *Request) send(ctx context.Context) (*http.Response, error) {
+	if r.replaying {
+		return r.resend(ctx)
+	}
+
+	jsonAPI := r.api
+	var request *http.Request
+	var err error
+	path := r.path
+	if r.pathParams != nil {
+		path, err = expandPath(path, r.pathParams)
+		if err != nil {
+			return nil, err
+		}
+	}
+	baseURL, baseQuery := joinURL(jsonAPI.BaseURL, path)
+	if r.absoluteURL != "" {
+		baseURL, baseQuery = joinURL(r.absoluteURL, "")
+	}
+	if r.sendsParamsAsForm() {
+		url := withQuery(baseURL, baseQuery)
+		form := strings.NewReader(r.encodeQuery())
+		request, err = http.NewRequestWithContext(ctx, r.method, url, form)
+		if err != nil {
+			return nil, err
+		}
+
+		required := http.Header{}
+		required.Set("Content-Type", "application/x-www-form-urlencoded")
+		return r.do(request, required, http.Header{})
+	}
+
+	url := withQuery(baseURL, joinQuery(baseQuery, r.encodeQuery()))
+	codec := r.bodyCodec()
+	compressed := false
+	// only bodies that were marshaled, or are JSON already, get a default
+	// Content-Type, raw bytes and streams are sent as whatever they are
+	defaultContentType := ""
+	if reader, ok := r.requestBody.(io.Reader); ok {
+		request, err = http.NewRequestWithContext(ctx, r.method, url,
+			ioutil.NopCloser(reader))
+	} else if raw, ok := rawRequestBody(r.requestBody); ok {
+		if _, ok := r.requestBody.(json.RawMessage); ok {
+			defaultContentType = "application/json"
+		}
+		request, err = http.NewRequestWithContext(ctx, r.method, url,
+			bytes.NewReader(raw))
+	} else if r.requestBody != nil {
+		defaultContentType = "application/json"
+		if codec != nil {
+			defaultContentType = codec.ContentType()
+		}
+		var serializedRequestBody []byte
+		if codec != nil {
+			serializedRequestBody, err = codec.Marshal(r.requestBody)
+		} else {
+			serializedRequestBody, err = json.Marshal(r.requestBody)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if jsonAPI.CompressRequests {
+			serializedRequestBody, err = gzipBytes(serializedRequestBody)
+			if err != nil {
+				return nil, err
+			}
+			compressed = true
+		}
+
+		serializedRequestBodyReader := bytes.NewReader(serializedRequestBody)
+		request, err = http.NewRequestWithContext(ctx, r.method, url,
+			serializedRequestBodyReader)
+	} else if r.rawBody != nil {
+		request, err = http.NewRequestWithContext(ctx, r.method, url,
+			bytes.NewReader(r.rawBody))
+	} else {
+		request, err = http.NewRequestWithContext(ctx, r.method, url, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// a Content-Type the body was built for, like a multipart boundary, is
+	// required, a guessed one only applies when nothing else set it
+	required := http.Header{}
+	defaults := http.Header{}
+	if r.requestBody != nil {
+		if r.contentType != "" {
+			required.Set("Content-Type", r.contentType)
+		} else if defaultContentType != "" {
+			defaults.Set("Content-Type", defaultContentType)
+		}
+	}
+	if codec != nil {
+		defaults.Set("Accept", codec.ContentType())
+	}
+	if compressed {
+		defaults.Set("Content-Encoding", "gzip")
+	}
+	return r.do(request, required, defaults)
+}
+
+// joinURL joins path to baseURL with a single slash between them, or
+// returns path when there is no base URL, the queries of both are merged
+// and returned separately
+func joinURL(baseURL, path string) (string, string) {
+	baseURL, baseQuery := splitQuery(baseURL)
+	path, pathQuery := splitQuery(path)
+	if baseURL == "" {
+		baseURL = path
+	} else if path != "" {
+		baseURL = strings.TrimRight(baseURL, "/") + "/" + strings.TrimLeft(path, "/")
+	}
+	return baseURL, joinQuery(baseQuery, pathQuery)
+}
+
+func splitQuery(rawURL string) (string, string) {
+	if question := strings.IndexByte(rawURL, '?'); question != -1 {
+		return rawURL[:question], rawURL[question+1:]
+	}
+	return rawURL, ""
+}
+
+func joinQuery(first, second string) string {
+	if first == "" || second == "" {
+		return first + second
+	}
+	return first + "&" + second
+}
+
+func withQuery(url, query string) string {
+	if query == "" {
+		return url
+	}
+	return url + "?" + query
+}
+
+// loggedRequest copies request for OnRequest with a fresh copy of its body,
+// or no body when it can only be read once
+func loggedRequest(request *http.Request) *http.Request {
+	logged := request.Clone(request.Context())
+	logged.Body = http.NoBody
+	if request.GetBody != nil {
+		body, err := request.GetBody()
+		if err == nil {
+			logged.Body = body
+		}
+	}
+	return logged
+}
+
+// rawRequestBody returns request bodies that are sent as they are instead
+// of being marshaled
+func rawRequestBody(requestBody interface{}) ([]byte, bool) {
+	switch body := requestBody.(type) {
+	case []byte:
+		return body, true
+	case json.RawMessage:
+		return body, true
+	}
+	return nil, false
+}
+
+// do applies the headers and hooks to request and sends it, required headers
+// override the JSONAPI's Headers but not the request's own, defaults are only
+// set for headers that are still missing after that
+func (r *Request) do(request *http.Request, required, defaults http.Header) (*http.Response, error) {
+	jsonAPI := r.api
+	headers, plugins, responseMiddleware := jsonAPI.hooks()
+	request.Close = r.forceClose
+	if jsonAPI.requestIDHeader != "" {
+		if r.id == "" {
+			r.id = jsonAPI.requestIDGenerator()
+		}
+		request.Header.Set(jsonAPI.requestIDHeader, r.id)
+	}
+
+	jsonAPI.setContextHeaders(request)
+
+	var err error
+	if jsonAPI.credentialProvider != nil {
+		err = jsonAPI.credentialProvider.Apply(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hostHeaders := jsonAPI.headersForHost(request.URL)
+	for name, value := range hostHeaders {
+		request.Header.Set(name, value)
+	}
+	for name, value := range headers {
+		_, hostHeader := hostHeaders[name]
+		_, requestHeader := r.header[http.CanonicalHeaderKey(name)]
+		if !hostHeader && !requestHeader && request.Header.Get(name) == "" {
+			request.Header.Set(name, value)
+		}
+	}
+	for name, values := range required {
+		request.Header[name] = values
+	}
+	for name, values := range r.header {
+		request.Header[name] = values
+	}
+	if jsonAPI.acceptLanguage != "" {
+		defaults.Set("Accept-Language", jsonAPI.acceptLanguage)
+	}
+	for name, values := range defaults {
+		if _, ok := request.Header[name]; !ok {
+			request.Header[name] = values
+		}
+	}
+	if jsonAPI.deadlineHeader != "" {
+		if deadline, ok := request.Context().Deadline(); ok {
+			remaining := time.Until(deadline).Milliseconds()
+			if remaining < 0 {
+				remaining = 0
+			}
+			request.Header.Set(jsonAPI.deadlineHeader, strconv.FormatInt(remaining, 10))
+		}
+	}
+
+	for _, plugin := range plugins {
+		err = plugin.BeforeRequest(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if jsonAPI.byteBudget != nil {
+		err = jsonAPI.byteBudget.charge(request)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r.capture(request)
+	if jsonAPI.OnRequest != nil {
+		jsonAPI.OnRequest(loggedRequest(request))
+	}
+	start := jsonAPI.now()
+	var response *http.Response
+	if r.transport != nil {
+		response, err = (&http.Client{Transport: r.transport}).Do(request)
+	} else {
+		response, err = jsonAPI.roundTrip(request)
+	}
+	if err != nil {
+		return nil, transmitError{err}
+	}
+	if jsonAPI.OnResponse != nil {
+		jsonAPI.OnResponse(response, jsonAPI.now().Sub(start))
+	}
+
+	if jsonAPI.byteBudget != nil {
+		response.Body = &countingBody{ReadCloser: response.Body, budget: jsonAPI.byteBudget}
+	}
+
+	for _, plugin := range plugins {
+		err = plugin.AfterResponse(response)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+	}
+	for _, middleware := range responseMiddleware {
+		err = middleware(response)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+	}
+
+	err = decodeGzip(response)
+	if err != nil {
+		response.Body.Close()
+		return nil, err
+	}
+
+	if jsonAPI.sniffCompression {
+		err = sniffGzip(response)
+		if err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+	}
+
+	if jsonAPI.onWarning != nil {
+		handleWarnings(response, jsonAPI.onWarning)
+	}
+
+	if jsonAPI.responseTee != nil {
+		response.Body = teeReadCloser{
+			Reader: io.TeeReader(response.Body, jsonAPI.responseTee),
+			Closer: response.Body,
+		}
+	}
+	r.response = response
+	return response, nil
+}
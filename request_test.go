@@ -0,0 +1,839 @@
+package jsonapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// echoServer responds with the method, query and body of every request
+func echoServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(echo{r.Method, r.URL.RawQuery, string(body)})
+	}))
+}
+
+type echo struct {
+	Method string
+	Query  string
+	Body   string
+}
+
+func TestRequestBuilder(t *testing.T) {
+	server := echoServer()
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result echo
+	response, err := jsonAPI.Request().Method("POST").Path("/x").Query("a", "1").
+		Body(map[string]int{"z": 1}).Into(&result).Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := echo{"POST", "a=1", `{"z":1}`}
+	if response.StatusCode != http.StatusOK || result != want {
+		t.Fatalf("result = %+v, want %+v", result, want)
+	}
+}
+
+func TestRequestOnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	status := 0
+	response, err := jsonAPI.Request().OnError(func(statusCode int, _, _ string) {
+		status = statusCode
+	}).Send()
+	if err == nil || status != http.StatusNotFound || response.StatusCode != http.StatusNotFound {
+		t.Fatalf("err = %v, OnError got %d", err, status)
+	}
+}
+
+func TestPeekBytes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json" {
+			w.Write([]byte(`{"a":1}`))
+			return
+		}
+		w.Write([]byte(`hello`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	for _, path := range []string{"/json", "/text"} {
+		var result map[string]int
+		var peeked string
+		var request *Request
+		request = jsonAPI.Request().Path(path).Into(&result).PeekBytes(1, func(peek []byte) error {
+			peeked = string(peek)
+			if peeked != "{" {
+				request.Into(DiscardBody)
+			}
+			return nil
+		})
+		if _, err := request.Send(); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if path == "/json" && (peeked != "{" || result["a"] != 1) {
+			t.Errorf("%s: peeked %q, result %v", path, peeked, result)
+		}
+		if path == "/text" && peeked != "h" {
+			t.Errorf("%s: peeked %q", path, peeked)
+		}
+	}
+}
+
+func TestParamsAsForm(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, r.URL.RawQuery+"|"+string(body)+"|"+r.Header.Get("Content-Type"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	parameters := url.Values{"a": {"1"}}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Post("/", parameters, nil, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.SetParamsAsForm(true)
+	jsonAPI.Post("/", parameters, nil, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.Post("/", parameters, 5, nil, func() {}, onHTTPError, onInternalError)
+	want := []string{
+		"a=1||",
+		"|a=1|application/x-www-form-urlencoded",
+		"a=1|5|application/json",
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Fatalf("received %q, want %q", received, want)
+	}
+}
+
+func TestParamsAsFormIgnoresHeadersContentType(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		received = append(received, string(body)+"|"+r.Header.Get("Content-Type"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, Headers: map[string]string{"Content-Type": "application/json"}}
+	jsonAPI.SetParamsAsForm(true)
+	parameters := url.Values{"a": {"1"}}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Post("/", parameters, nil, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.Request().SetHeader("Content-Type", "text/x-form").
+		Post("/", parameters, nil, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.SetParamsAsForm(false)
+	jsonAPI.Headers["Content-Type"] = "application/vnd.api+json"
+	jsonAPI.Post("/", nil, 5, nil, func() {}, onHTTPError, onInternalError)
+	want := []string{
+		"a=1|application/x-www-form-urlencoded",
+		"a=1|text/x-form",
+		"5|application/vnd.api+json",
+	}
+	if !reflect.DeepEqual(received, want) {
+		t.Fatalf("received %q, want %q", received, want)
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	server := jsonServer(`{"a":"0123456789"}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result map[string]string
+	if _, err := jsonAPI.Request().Into(&result).SetMaxResponseBytes(18).Send(); err != nil {
+		t.Fatal(err)
+	}
+	_, err := jsonAPI.Request().Into(&result).SetMaxResponseBytes(17).Send()
+	if err != ErrResponseTooLarge {
+		t.Fatalf("err = %v, want ErrResponseTooLarge", err)
+	}
+}
+
+func TestForceClose(t *testing.T) {
+	var closes []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		closes = append(closes, r.Close)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.Request().ForceClose().Send()
+	jsonAPI.Request().Send()
+	if !reflect.DeepEqual(closes, []bool{true, false}) {
+		t.Fatalf("closes = %v, want [true false]", closes)
+	}
+}
+
+func TestPathParams(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.RequestURI)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	_, err := jsonAPI.Request().PathParams("/users/{id}/posts/{postId}",
+		map[string]string{"id": "a b/c?", "postId": "7"}).Send()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().Query("page", "2").QueryInt("per", 50).PathParam("id", "a/b 1").
+		Get("/users/{id}", url.Values{"sort": {"name"}}, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.Request().PathParam("id", "7").Delete("/users/{id}", nil, nil, func() {},
+		onHTTPError, onInternalError)
+	want := []string{
+		"GET /users/a%20b%2Fc%3F/posts/7",
+		"GET /users/a%2Fb%201?page=2&per=50&sort=name",
+		"DELETE /users/7",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("paths = %q, want %q", paths, want)
+	}
+
+	_, err = jsonAPI.Request().PathParams("/users/{id}", nil).Send()
+	if err == nil || len(paths) != 3 {
+		t.Fatalf("missing path param: err = %v after %d requests", err, len(paths))
+	}
+}
+
+func TestExpectStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	status := 0
+	_, err := jsonAPI.Request().Method("POST").ExpectStatus(http.StatusCreated).
+		OnError(func(statusCode int, _, _ string) {
+			status = statusCode
+		}).Send()
+	if err == nil || status != http.StatusOK {
+		t.Fatalf("err = %v, OnError got %d, want the 200 as an error", err, status)
+	}
+	if _, err := jsonAPI.Request().ExpectStatus(http.StatusCreated, http.StatusOK).Send(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Write([]byte(`[`))
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := jsonAPI.Request().WithContext(ctx).Send()
+	if err != context.Canceled || hits != 0 {
+		t.Fatalf("err = %v with %d requests, want context.Canceled before sending", err, hits)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	var result interface{}
+	_, err = jsonAPI.Request().WithContext(ctx).Path("/items").Into(&result).Send()
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled while reading the body", err)
+	}
+}
+
+type requestIDKey struct{}
+
+type requestIDPlugin struct{}
+
+func (requestIDPlugin) BeforeRequest(request *http.Request) error {
+	id, _ := request.Context().Value(requestIDKey{}).(string)
+	request.Header.Set("X-Request-ID", id)
+	return nil
+}
+
+func (requestIDPlugin) AfterResponse(*http.Response) error { return nil }
+
+func TestRequestValues(t *testing.T) {
+	var sent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = r.Header.Get("X-Request-ID")
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.AddPlugin(requestIDPlugin{})
+	var middlewareID interface{}
+	jsonAPI.UseResponse(func(response *http.Response) error {
+		middlewareID = response.Request.Context().Value(requestIDKey{})
+		return nil
+	})
+	withRequestID := func(request *Request) *Request {
+		return request.WithValue(requestIDKey{}, "req-1")
+	}
+
+	request := withRequestID(jsonAPI.Request())
+	var callbackID interface{}
+	request.Get("/", nil, nil, func() {
+		t.Error("404 succeeded")
+	}, func(statusCode int, statusMessage, errorMessage string) {
+		callbackID = request.Value(requestIDKey{})
+	}, func(err error) {
+		t.Error(err)
+	})
+	if sent != "req-1" || middlewareID != "req-1" || callbackID != "req-1" {
+		t.Fatalf("plugin sent %q, middleware got %v, callback got %v, want req-1",
+			sent, middlewareID, callbackID)
+	}
+	if value := jsonAPI.Request().Value(requestIDKey{}); value != nil {
+		t.Fatalf("fresh request has value %v", value)
+	}
+}
+
+func TestOrderedQuery(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.Request().Query("z", "1").Query("a", "2 3").Query("z", "0").OrderedQuery().Send()
+	jsonAPI.Request().Query("z", "1").Query("a", "2").Send()
+	want := []string{"z=1&a=2+3&z=0", "a=2&z=1"}
+	if !reflect.DeepEqual(queries, want) {
+		t.Fatalf("queries = %q, want %q", queries, want)
+	}
+}
+
+func TestJSONContentType(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, fmt.Sprint(r.Header.Get("Content-Type"), "|", r.ContentLength))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Post("/", nil, map[string]int{"a": 1}, nil, func() {}, onHTTPError, onInternalError)
+	jsonAPI.Request().Method("POST").Body(1).SetHeader("content-type", "application/vnd.api+json").Send()
+	jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, onInternalError)
+	custom := &JSONAPI{BaseURL: server.URL, Headers: map[string]string{"content-type": "application/x"}}
+	custom.Post("/", nil, 1, nil, func() {}, onHTTPError, onInternalError)
+	want := []string{"application/json|7", "application/vnd.api+json|1", "|0", "application/x|1"}
+	if !reflect.DeepEqual(received, want) {
+		t.Fatalf("received %q, want %q", received, want)
+	}
+}
+
+func TestNoBareQuestionMark(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	tests := []struct {
+		parameters url.Values
+		want       string
+	}{
+		{nil, "/x"},
+		{url.Values{}, "/x"},
+		{url.Values{"a": {"1 2"}, "b": {"3"}}, "/x?a=1+2&b=3"},
+	}
+	for _, test := range tests {
+		jsonAPI.Get("/x", test.parameters, nil, func() {}, onHTTPError, onInternalError)
+		if requestURI != test.want {
+			t.Errorf("%v: requested %q, want %q", test.parameters, requestURI, test.want)
+		}
+	}
+}
+
+func TestRequestTransport(t *testing.T) {
+	jsonAPI := &JSONAPI{BaseURL: "http://example.invalid"}
+	transport := roundTripFunc(func(request *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(`{"a":2}`)),
+			Request:    request,
+		}, nil
+	})
+	var result struct{ A int }
+	if _, err := jsonAPI.Request().SetTransport(transport).Into(&result).Send(); err != nil {
+		t.Fatal(err)
+	}
+	if result.A != 2 {
+		t.Fatalf("result = %+v, want the transport's body", result)
+	}
+}
+
+type roundTripFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestErrorReturningVerbs(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"nope","message":"missing"}`))
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		json.NewEncoder(w).Encode(echo{Method: r.Method, Body: string(body)})
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result echo
+	if err := jsonAPI.Request().PostJSON("/", nil, 5, &result); err != nil || result.Body != "5" {
+		t.Fatalf("PostJSON: err = %v, result = %+v", err, result)
+	}
+	if err := jsonAPI.Request().PutJSON("/", nil, 6, &result); err != nil || result.Method != "PUT" {
+		t.Fatalf("PutJSON: err = %v, result = %+v", err, result)
+	}
+	if err := jsonAPI.Request().DeleteJSON("/", nil, &result); err != nil || result.Method != "DELETE" {
+		t.Fatalf("DeleteJSON: err = %v, result = %+v", err, result)
+	}
+
+	err := jsonAPI.Request().GetJSON("/missing", nil, &result)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Details.Status != http.StatusNotFound ||
+		httpErr.Details.Message != "missing" || err.Error() != "jsonapi: nope" {
+		t.Fatalf("err = %v, want the decoded 404", err)
+	}
+}
+
+func TestOnSuccessStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Location", "/items/9")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"a":1}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	status := 0
+	location := ""
+	err := jsonAPI.Request().OnSuccess(func(statusCode int, header http.Header) {
+		status, location = statusCode, header.Get("Location")
+	}).PostJSON("/items", nil, 1, nil)
+	if err != nil || status != http.StatusCreated || location != "/items/9" {
+		t.Fatalf("err = %v, status = %d, location = %q", err, status, location)
+	}
+
+	order := ""
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().OnSuccess(func(int, http.Header) {
+		order += "status,"
+	}).Patch("/items", nil, 1, nil, func() {
+		order += "callback"
+	}, onHTTPError, onInternalError)
+	if order != "status,callback" {
+		t.Fatalf("order = %q, want OnSuccess first", order)
+	}
+}
+
+func TestStatusErrorMap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		statusCode, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		w.WriteHeader(statusCode)
+	}))
+	defer server.Close()
+
+	errNotFound := errors.New("not found")
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetStatusErrorMap(map[int]error{http.StatusNotFound: errNotFound})
+	err := jsonAPI.Request().GetJSON("/404", nil, nil)
+	var httpErr *HTTPError
+	if !errors.Is(err, errNotFound) || !errors.As(err, &httpErr) || err.Error() != "not found" {
+		t.Fatalf("err = %v, want the mapped error", err)
+	}
+	err = jsonAPI.Request().GetJSON("/500", nil, nil)
+	if err == nil || err.Error() != "jsonapi: 500 Internal Server Error" {
+		t.Fatalf("err = %v, want the unmapped status", err)
+	}
+}
+
+func TestRateLimitError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/limited" {
+			w.Header().Set("Retry-After", "30")
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	err := jsonAPI.Request().GetJSON("/limited", nil, nil)
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 30*time.Second {
+		t.Fatalf("err = %#v, want a *RateLimitError with RetryAfter 30s", err)
+	}
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Details.Status != http.StatusTooManyRequests {
+		t.Fatalf("err = %v, want it to wrap the 429 *HTTPError", err)
+	}
+
+	err = jsonAPI.Request().GetJSON("/", nil, nil)
+	if !errors.As(err, &rateLimitErr) || rateLimitErr.RetryAfter != 0 {
+		t.Fatalf("err = %#v, want a *RateLimitError without RetryAfter", err)
+	}
+}
+
+func TestRawBodies(t *testing.T) {
+	var body []byte
+	var contentLength int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+		contentLength = r.ContentLength
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	payload := "raw \x00\xff bytes"
+	tests := []struct {
+		body          interface{}
+		want          string
+		contentLength int64
+	}{
+		{strings.NewReader(payload), payload, -1},
+		{[]byte(payload), payload, int64(len(payload))},
+		{json.RawMessage(`{"a": 1}`), `{"a": 1}`, 8},
+		{"s", `"s"`, 3},
+	}
+	for _, test := range tests {
+		if _, err := jsonAPI.Request().Method("POST").Body(test.body).Send(); err != nil {
+			t.Fatal(err)
+		}
+		if string(body) != test.want || contentLength != test.contentLength {
+			t.Errorf("%T body arrived as %q with length %d", test.body, body, contentLength)
+		}
+	}
+}
+
+func TestDropEmptyParams(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.RawQuery
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	parameters := url.Values{"a": {""}, "b": {"1"}, "c": {"", "2"}}
+	jsonAPI.Request().Query("d", "").Query("e", "3").GetJSON("/", parameters, nil)
+	if query != "a=&b=1&c=&c=2&d=&e=3" {
+		t.Fatalf("query = %q, want empty values kept", query)
+	}
+	jsonAPI.SetDropEmptyParams(true)
+	jsonAPI.Request().Query("d", "").Query("e", "3").GetJSON("/", parameters, nil)
+	if query != "b=1&c=&c=2&e=3" {
+		t.Fatalf("query = %q, want empty values dropped", query)
+	}
+}
+
+func TestHeaderPrecedence(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, Headers: map[string]string{
+		"authorization": "Bearer api",
+		"X-A":           "1",
+	}}
+	jsonAPI.Request().SetHeader("Authorization", "Bearer request").GetJSON("/", nil, nil)
+	if !reflect.DeepEqual(header["Authorization"], []string{"Bearer request"}) || header.Get("X-A") != "1" {
+		t.Fatalf("header = %v, want the request header to win", header)
+	}
+	jsonAPI.Request().GetJSON("/", nil, nil)
+	if !reflect.DeepEqual(header["Authorization"], []string{"Bearer api"}) {
+		t.Fatalf("header = %v, want the shared header", header)
+	}
+}
+
+func TestMaxForwards(t *testing.T) {
+	var method, maxForwards string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, maxForwards = r.Method, r.Header.Get("Max-Forwards")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	_, err := jsonAPI.Request().Method("TRACE").SetMaxForwards(0).Into(DiscardBody).Send()
+	if err != nil || method != "TRACE" || maxForwards != "0" {
+		t.Fatalf("err = %v, method = %s, Max-Forwards = %q", err, method, maxForwards)
+	}
+}
+
+func TestJoinURL(t *testing.T) {
+	var requestURI string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestURI = r.RequestURI
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		base       string
+		path       string
+		parameters url.Values
+		want       string
+	}{
+		{"/v1/", "/users", nil, "/v1/users"},
+		{"/v1", "users", nil, "/v1/users"},
+		{"/v1", "/users", nil, "/v1/users"},
+		{"/v1/", "", nil, "/v1/"},
+		{"", "/users/", nil, "/users/"},
+		{"/v1?key=k", "/users", url.Values{"a": {"1"}}, "/v1/users?key=k&a=1"},
+		{"/v1", "/users?x=1", url.Values{"a": {"1"}}, "/v1/users?x=1&a=1"},
+		{"/v1", "/a%2Fb", nil, "/v1/a%2Fb"},
+		{"/v1", "/x", url.Values{}, "/v1/x"},
+	}
+	for _, test := range tests {
+		jsonAPI := &JSONAPI{BaseURL: server.URL + test.base}
+		if err := jsonAPI.Request().GetJSON(test.path, test.parameters, nil); err != nil {
+			t.Fatal(err)
+		}
+		if requestURI != test.want {
+			t.Errorf("%q + %q = %q, want %q", test.base, test.path, requestURI, test.want)
+		}
+	}
+}
+
+func TestLogHooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		time.Sleep(5 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var logged string
+	var elapsed time.Duration
+	jsonAPI.OnRequest = func(request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+		logged = request.Method + " " + string(body)
+	}
+	jsonAPI.OnResponse = func(response *http.Response, duration time.Duration) {
+		elapsed = duration
+	}
+	if err := jsonAPI.Request().PostJSON("/", nil, map[string]int{"a": 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if logged != `POST {"a":1}` || elapsed < 5*time.Millisecond {
+		t.Fatalf("logged %q after %v", logged, elapsed)
+	}
+}
+
+func TestStrictMethodBodies(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var reported string
+	jsonAPI.OnUnexpectedBody(func(method, path string) {
+		reported = method + " " + path
+	})
+	_, err := jsonAPI.Request().Path("/things").Body(1).Send()
+	if err != nil || hits != 1 {
+		t.Fatalf("lenient GET with a body: err = %v, hits = %d", err, hits)
+	}
+	if reported != "GET /things" {
+		t.Fatalf("reported %q, want GET /things", reported)
+	}
+
+	jsonAPI.SetStrictMethodBodies(true)
+	_, err = jsonAPI.Request().Path("/things").Body(1).Send()
+	if err != ErrUnexpectedBody || hits != 1 {
+		t.Fatalf("strict GET with a body: err = %v, hits = %d", err, hits)
+	}
+	_, err = jsonAPI.Request().Method("POST").Path("/things").Body(1).Send()
+	if err != nil || hits != 2 {
+		t.Fatalf("strict POST with a body: err = %v, hits = %d", err, hits)
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			select {
+			case <-r.Context().Done():
+			case <-time.After(time.Second):
+			}
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var err error
+	jsonAPI.Request().Timeout(20*time.Millisecond).Patch("/", nil, 1, nil, func() {
+		t.Error("slow request succeeded")
+	}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+	if _, err := jsonAPI.Request().Timeout(time.Second).Send(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrefer(t *testing.T) {
+	var prefer string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		prefer = r.Header.Get("Prefer")
+		w.Header().Set("Preference-Applied", "return=minimal")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	request := jsonAPI.Request().Method("POST").SetPrefer("return=minimal", "respond-async")
+	if request.PreferenceApplied() != "" {
+		t.Fatal("preference applied before sending")
+	}
+	if _, err := request.Send(); err != nil {
+		t.Fatal(err)
+	}
+	if prefer != "return=minimal, respond-async" || request.PreferenceApplied() != "return=minimal" {
+		t.Fatalf("Prefer = %q, applied = %q", prefer, request.PreferenceApplied())
+	}
+}
+
+func TestRedirectsSucceed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/moved" {
+			w.Header().Set("Location", "/elsewhere")
+			w.WriteHeader(http.StatusFound)
+			w.Write([]byte("<html>moved</html>"))
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, Client: &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+	var result struct{ A int }
+	status := 0
+	onSuccess := func(statusCode int, _ http.Header) {
+		status = statusCode
+	}
+	err := jsonAPI.Request().SetHeader("If-None-Match", `"v1"`).OnSuccess(onSuccess).
+		GetJSON("/", nil, &result)
+	if err != nil || status != http.StatusNotModified {
+		t.Fatalf("err = %v, status = %d, want a 304 success", err, status)
+	}
+	err = jsonAPI.Request().OnSuccess(onSuccess).GetJSON("/moved", nil, &result)
+	if err != nil || status != http.StatusFound {
+		t.Fatalf("err = %v, status = %d, want a 302 success", err, status)
+	}
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err == nil {
+		t.Fatal("400 is not an error")
+	}
+}
+
+func TestBodyContentType(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	tests := []struct {
+		body interface{}
+		want string
+	}{
+		{map[string]int{"a": 1}, "application/json"},
+		{json.RawMessage(`{"a":1}`), "application/json"},
+		{[]byte("raw"), ""},
+		{strings.NewReader("streamed"), ""},
+	}
+	for _, test := range tests {
+		_, err := jsonAPI.Request().Method("POST").Body(test.body).Send()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if contentType != test.want {
+			t.Errorf("%T body sent Content-Type %q, want %q", test.body, contentType, test.want)
+		}
+	}
+}
+
+func TestHeadAndOptionsResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "HEAD":
+			w.Header().Set("ETag", `"1"`)
+		case "OPTIONS":
+			w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		}
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	response, err := jsonAPI.Request().HeadResponse("/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if etag := response.Header.Get("ETag"); etag != `"1"` {
+		t.Errorf("ETag = %s", etag)
+	}
+
+	response, err = jsonAPI.Request().OptionsResponse("/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if allow := response.Header.Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Errorf("Allow = %s", allow)
+	}
+
+	_, err = jsonAPI.Request().HeadResponse("/missing", nil)
+	httpErr, ok := err.(*HTTPError)
+	if !ok || httpErr.Details.Status != http.StatusNotFound {
+		t.Fatalf("err = %v, want a 404 *HTTPError", err)
+	}
+}
@@ -0,0 +1,30 @@
+package jsonapi
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// SetRequestIDHeader makes every request carry a unique ID in the header
+// name, generated with generate or as a random UUID when generate is nil
+func (jsonAPI *JSONAPI) SetRequestIDHeader(name string, generate func() string) {
+	if generate == nil {
+		generate = newUUID
+	}
+	jsonAPI.requestIDHeader = name
+	jsonAPI.requestIDGenerator = generate
+}
+
+// ID returns the ID the request was sent with, see SetRequestIDHeader
+func (r *Request) ID() string {
+	return r.id
+}
+
+func newUUID() string {
+	var uuid [16]byte
+	rand.Read(uuid[:])
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10],
+		uuid[10:])
+}
@@ -0,0 +1,24 @@
+package jsonapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDHeader(t *testing.T) {
+	var ids []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get("X-Request-ID"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetRequestIDHeader("X-Request-ID", nil)
+	request := jsonAPI.Request()
+	request.Send()
+	jsonAPI.Request().Send()
+	if len(ids[0]) != 36 || ids[0] != request.ID() || ids[0] == ids[1] {
+		t.Fatalf("ids = %q, request.ID() = %q", ids, request.ID())
+	}
+}
@@ -0,0 +1,194 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy retries failed requests with exponential backoff, only GET,
+// PUT and DELETE requests are retried unless RetryPOST is set
+type RetryPolicy struct {
+	MaxRetries int
+	// BaseDelay is the wait before the first retry, it doubles after every
+	// attempt
+	BaseDelay time.Duration
+	// RetryOn decides whether an attempt is retried, by default network
+	// errors and 5xx responses are, except for unknown hosts and refused
+	// connections, errors raised before the request is sent are never
+	// retried
+	RetryOn   func(response *http.Response, err error) bool
+	RetryPOST bool
+	// MaxRetryAfter caps the wait asked for by the Retry-After header of 429
+	// and 503 responses, which are always retried, 0 means no cap
+	MaxRetryAfter time.Duration
+}
+
+// RetryCallback runs before the wait for a retry, attempt counts retries from
+// 1, lastErr and lastStatus are the error and status of the failed attempt,
+// lastStatus is 0 when there was no response
+type RetryCallback func(attempt int, lastErr error, lastStatus int)
+
+// OnRetry sets a callback that runs before every retry of the request
+func (r *Request) OnRetry(onRetry RetryCallback) *Request {
+	r.onRetry = onRetry
+	return r
+}
+
+// SetRetryBodyPredicate makes the RetryPolicy retry 2xx responses whose body
+// matches retryable, like {"status":"retryable_error"}, the bodies of 2xx
+// responses are read into memory to check them, nil turns it off
+func (jsonAPI *JSONAPI) SetRetryBodyPredicate(retryable func(body []byte) bool) {
+	jsonAPI.retryBodyPredicate = retryable
+}
+
+func (policy *RetryPolicy) retries(method string) bool {
+	switch method {
+	case "GET", "PUT", "DELETE":
+		return true
+	case "POST":
+		return policy.RetryPOST
+	}
+	return false
+}
+
+func (policy *RetryPolicy) shouldRetry(response *http.Response, err error) bool {
+	if _, ok := err.(transmitError); err != nil && !ok {
+		return false
+	}
+	err = unwrapTransmit(err)
+	if policy.RetryOn != nil {
+		return policy.RetryOn(response, err)
+	}
+	if err != nil {
+		return !permanent(err)
+	}
+	return response.StatusCode >= 500
+}
+
+// transmitError marks an error returned by the round trip itself, as
+// opposed to one raised while the request was being prepared
+type transmitError struct {
+	err error
+}
+
+func (err transmitError) Error() string {
+	return err.err.Error()
+}
+
+func unwrapTransmit(err error) error {
+	if transmitErr, ok := err.(transmitError); ok {
+		return transmitErr.err
+	}
+	return err
+}
+
+// permanent reports whether a network error will not go away by trying
+// again
+func permanent(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// sendWithRetries sends the request again as long as the retry policy
+// allows it, the body is serialized anew for every attempt and io.Reader
+// bodies, which can only be read once, are never retried
+func (r *Request) sendWithRetries(ctx context.Context) (*http.Response, error) {
+	jsonAPI := r.api
+	policy := jsonAPI.RetryPolicy
+	response, err := r.send(ctx)
+	_, streamed := r.requestBody.(io.Reader)
+	if policy == nil || streamed || !policy.retries(r.method) {
+		return response, unwrapTransmit(err)
+	}
+
+	delay := policy.BaseDelay
+	for attempt := 0; attempt < policy.MaxRetries; attempt++ {
+		retryAfter, ok := jsonAPI.retryAfter(response)
+		retryBody, bodyErr := jsonAPI.retryableBody(response)
+		if bodyErr != nil {
+			return nil, bodyErr
+		}
+		if ctx.Err() != nil || !ok && !retryBody && !policy.shouldRetry(response, err) {
+			break
+		}
+		lastStatus := 0
+		if response != nil {
+			lastStatus = response.StatusCode
+			response.Body.Close()
+		}
+
+		wait := delay
+		if ok {
+			wait = retryAfter
+			if policy.MaxRetryAfter > 0 && wait > policy.MaxRetryAfter {
+				wait = policy.MaxRetryAfter
+			}
+		}
+		if r.onRetry != nil {
+			r.onRetry(attempt+1, unwrapTransmit(err), lastStatus)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-jsonAPI.after(wait):
+		}
+		delay *= 2
+		response, err = r.send(ctx)
+	}
+	return response, unwrapTransmit(err)
+}
+
+// retryableBody reports whether the body of a 2xx response matches the
+// retry body predicate, the body is put back in memory so it can still be
+// decoded when the response is not retried
+func (jsonAPI *JSONAPI) retryableBody(response *http.Response) (bool, error) {
+	if jsonAPI.retryBodyPredicate == nil || response == nil ||
+		response.StatusCode < 200 || response.StatusCode >= 300 {
+		return false, nil
+	}
+
+	body, err := ioutil.ReadAll(response.Body)
+	response.Body.Close()
+	if err != nil {
+		return false, err
+	}
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return jsonAPI.retryBodyPredicate(body), nil
+}
+
+// retryAfter parses the Retry-After header of a 429 or 503 response, as
+// either delay seconds or an HTTP date
+func (jsonAPI *JSONAPI) retryAfter(response *http.Response) (time.Duration, bool) {
+	if response == nil || response.StatusCode != http.StatusTooManyRequests &&
+		response.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	value := response.Header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+	wait := date.Sub(jsonAPI.now())
+	if wait < 0 {
+		wait = 0
+	}
+	return wait, true
+}
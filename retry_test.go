@@ -0,0 +1,276 @@
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Second,
+	}}
+	clock := &fakeClock{}
+	jsonAPI.SetClock(clock)
+	err := jsonAPI.Request().GetJSON("/", nil, nil)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.Details.Status != http.StatusServiceUnavailable {
+		t.Fatalf("err = %v, want a 503 *HTTPError", err)
+	}
+	if attempts != 4 {
+		t.Fatalf("attempts = %d, want 4", attempts)
+	}
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	if !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("waits = %v, want %v", clock.waits, want)
+	}
+}
+
+func TestOnRetry(t *testing.T) {
+	statuses := []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(statuses[attempts])
+		attempts++
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  time.Second,
+	}}
+	clock := &fakeClock{}
+	jsonAPI.SetClock(clock)
+	var retries []string
+	err := jsonAPI.Request().OnRetry(func(attempt int, lastErr error, lastStatus int) {
+		retries = append(retries, fmt.Sprint(attempt, " ", lastErr, " ", lastStatus, " ", len(clock.waits)))
+	}).GetJSON("/", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1 <nil> 503 0", "2 <nil> 502 1"}
+	if !reflect.DeepEqual(retries, want) {
+		t.Fatalf("retries = %q, want %q", retries, want)
+	}
+}
+
+func TestRetryBodyPredicate(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Write([]byte(`{"status":"retryable_error"}`))
+			return
+		}
+		w.Write([]byte(`{"status":"ok","value":7}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{MaxRetries: 3}}
+	jsonAPI.SetClock(&fakeClock{})
+	jsonAPI.SetRetryBodyPredicate(func(body []byte) bool {
+		return bytes.Contains(body, []byte(`"retryable_error"`))
+	})
+	var result struct {
+		Status string
+		Value  int
+	}
+	if err := jsonAPI.Request().GetJSON("/", nil, &result); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 || result.Status != "ok" || result.Value != 7 {
+		t.Fatalf("result = %+v after %d attempts, want the second body", result, attempts)
+	}
+}
+
+func TestRetryMethods(t *testing.T) {
+	attempts := 0
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{MaxRetries: 3}}
+	jsonAPI.SetClock(&fakeClock{})
+	if err := jsonAPI.Request().PutJSON("/", nil, 7, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(bodies, []string{"7", "7", "7"}) {
+		t.Fatalf("bodies = %q, want the body sent again on every attempt", bodies)
+	}
+
+	attempts = 0
+	if err := jsonAPI.Request().PostJSON("/", nil, 7, nil); err == nil || attempts != 1 {
+		t.Fatalf("POST: err = %v after %d attempts, want no retry", err, attempts)
+	}
+
+	attempts = 0
+	jsonAPI.RetryPolicy.RetryPOST = true
+	if err := jsonAPI.Request().PostJSON("/", nil, 7, nil); err != nil || attempts != 3 {
+		t.Fatalf("POST with RetryPOST: err = %v after %d attempts", err, attempts)
+	}
+}
+
+func TestRetryCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{
+		MaxRetries: 3,
+		BaseDelay:  time.Hour,
+	}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := jsonAPI.Request().WithContext(ctx).GetJSON("/", nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	attempts := 0
+	status := http.StatusTooManyRequests
+	retryAfter := "7"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	// RetryOn refusing everything shows Retry-After responses are retried
+	// regardless
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  time.Millisecond,
+		RetryOn:    func(*http.Response, error) bool { return false },
+	}}
+	clock := &fakeClock{}
+	jsonAPI.SetClock(clock)
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{7 * time.Second, 7 * time.Second}; !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("waits = %v, want %v", clock.waits, want)
+	}
+
+	attempts = 0
+	clock.waits = nil
+	status = http.StatusServiceUnavailable
+	retryAfter = clock.Now().Add(90 * time.Second).Format(http.TimeFormat)
+	jsonAPI.RetryPolicy.MaxRetryAfter = time.Minute
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{time.Minute, time.Minute}; !reflect.DeepEqual(clock.waits, want) {
+		t.Fatalf("waits = %v, want the capped %v", clock.waits, want)
+	}
+}
+
+func TestRetryServerErrors(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  time.Millisecond,
+	}}
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestRetrySkipsErrorsBeforeSending(t *testing.T) {
+	attempts := 0
+	providerErr := errors.New("no token")
+	jsonAPI := &JSONAPI{BaseURL: "http://127.0.0.1:1", RetryPolicy: &RetryPolicy{
+		MaxRetries: 5,
+		BaseDelay:  time.Millisecond,
+	}}
+	jsonAPI.SetTokenProvider(func(context.Context) (string, error) {
+		attempts++
+		return "", providerErr
+	})
+
+	err := jsonAPI.Request().GetJSON("/", nil, nil)
+	if !errors.Is(err, providerErr) {
+		t.Fatalf("err = %v, want %v", err, providerErr)
+	}
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestRetrySkipsConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	retried := false
+	jsonAPI := &JSONAPI{BaseURL: "http://" + address, RetryPolicy: &RetryPolicy{
+		MaxRetries: 1,
+		BaseDelay:  time.Millisecond,
+	}}
+	jsonAPI.OnRequest = func(*http.Request) {
+		if retried {
+			t.Error("connection refused was retried")
+		}
+		retried = true
+	}
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestPermanent(t *testing.T) {
+	if !permanent(&net.DNSError{Err: "no such host", Name: "x.invalid", IsNotFound: true}) {
+		t.Error("NXDOMAIN is not permanent")
+	}
+	if permanent(&net.DNSError{Err: "timeout", Name: "x.invalid", IsTimeout: true}) {
+		t.Error("DNS timeout is permanent")
+	}
+}
@@ -0,0 +1,67 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+)
+
+var rpcID uint64
+
+// RPCError is the error object of a JSON-RPC 2.0 response
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (err *RPCError) Error() string {
+	return fmt.Sprintf("jsonapi: RPC error %d: %s", err.Code, err.Message)
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      uint64      `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	ID     *uint64         `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *RPCError       `json:"error"`
+}
+
+// RPCCall calls a JSON-RPC 2.0 method and decodes its result into result, an
+// error object in the response is returned as an *RPCError
+func (jsonAPI *JSONAPI) RPCCall(url, method string, params interface{},
+	result interface{}) error {
+	request := rpcRequest{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&rpcID, 1),
+		Method:  method,
+		Params:  params,
+	}
+	var response rpcResponse
+	_, err := jsonAPI.Request().Method("POST").Path(url).Body(request).
+		Into(&response).Send()
+	if err != nil {
+		return err
+	}
+
+	if response.Error != nil && response.ID == nil {
+		return response.Error
+	}
+	if response.ID == nil || *response.ID != request.ID {
+		return fmt.Errorf("jsonapi: RPC response id does not match request id %d",
+			request.ID)
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
@@ -0,0 +1,42 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRPCCall(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			ID      uint64
+			Method  string
+			JSONRPC string
+		}
+		json.NewDecoder(r.Body).Decode(&request)
+		if request.JSONRPC != "2.0" {
+			t.Errorf("jsonrpc = %q, want 2.0", request.JSONRPC)
+		}
+		if request.Method == "add" {
+			fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"result":3}`, request.ID)
+			return
+		}
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%d,"error":{"code":-32601,"message":"Method not found"}}`, request.ID)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result int
+	if err := jsonAPI.RPCCall("/", "add", []int{1, 2}, &result); err != nil || result != 3 {
+		t.Fatalf("err = %v, result = %d", err, result)
+	}
+
+	err := jsonAPI.RPCCall("/", "missing", nil, &result)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != -32601 {
+		t.Fatalf("err = %v, want RPCError -32601", err)
+	}
+}
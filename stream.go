@@ -0,0 +1,181 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"strings"
+)
+
+// ErrNotArray is returned when a streamed response body is not a JSON array
+var ErrNotArray = errors.New("jsonapi: response body is not a JSON array")
+
+// ElementCallback runs for every element of a streamed JSON array
+type ElementCallback func(element json.RawMessage) error
+
+// GetArrayStream request, decoding a top level JSON array one element at a
+// time without buffering the whole body
+func (jsonAPI *JSONAPI) GetArrayStream(url string, parameters url.Values,
+	onElement ElementCallback, onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	jsonAPI.newRequest("GET", url, parameters, nil, nil).streamArray(
+		onElement, onSuccess, onHTTPError, onInternalError)
+}
+
+// GetArrayStream request, like JSONAPI.GetArrayStream but with the headers,
+// context and other settings of the request
+func (r *Request) GetArrayStream(url string, parameters url.Values,
+	onElement ElementCallback, onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	r.method = "GET"
+	r.path = url
+	r.parameters = parameters
+	r.streamArray(onElement, onSuccess, onHTTPError, onInternalError)
+}
+
+func (r *Request) streamArray(onElement ElementCallback, onSuccess SuccessCallback,
+	onHTTPError HTTPErrorCallback, onInternalError InternalErrorCallback) {
+	response, cancel := r.open(onHTTPError, onInternalError)
+	if response == nil {
+		return
+	}
+
+	defer cancel()
+	defer response.Body.Close()
+	if isRedirect(response.StatusCode) {
+		onSuccess()
+		return
+	}
+	decoder := json.NewDecoder(response.Body)
+	token, err := decoder.Token()
+	if err != nil {
+		onInternalError(err)
+		return
+	}
+	if token != json.Delim('[') {
+		onInternalError(ErrNotArray)
+		return
+	}
+
+	for decoder.More() {
+		var element json.RawMessage
+		err = decoder.Decode(&element)
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+
+		err = onElement(element)
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+	}
+
+	_, err = decoder.Token()
+	if err != nil {
+		onInternalError(err)
+		return
+	}
+
+	onSuccess()
+}
+
+// ChunkCallback runs for every piece of a streamed response body, chunk is
+// only valid until the callback returns
+type ChunkCallback func(chunk []byte) error
+
+// GetChunks request, calling onChunk with the response body as it arrives
+// instead of waiting for the whole body
+func (jsonAPI *JSONAPI) GetChunks(url string, parameters url.Values,
+	onChunk ChunkCallback, onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	response, cancel := jsonAPI.newRequest("GET", url, parameters, nil, nil).open(
+		onHTTPError, onInternalError)
+	if response == nil {
+		return
+	}
+
+	defer cancel()
+	defer response.Body.Close()
+	if isRedirect(response.StatusCode) {
+		onSuccess()
+		return
+	}
+	chunk := make([]byte, 32*1024)
+	for {
+		n, err := response.Body.Read(chunk)
+		if n > 0 {
+			callbackErr := onChunk(chunk[:n])
+			if callbackErr != nil {
+				onInternalError(callbackErr)
+				return
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+	}
+
+	onSuccess()
+}
+
+// ErrNotMultipart is returned when a response is not a multipart body
+var ErrNotMultipart = errors.New("jsonapi: response body is not multipart")
+
+// PartCallback runs for every part of a multipart response, body is only
+// valid until the callback returns
+type PartCallback func(header textproto.MIMEHeader, body io.Reader) error
+
+// GetMultipart request, calling onPart for every part of a multipart
+// response such as multipart/mixed as it is read
+func (jsonAPI *JSONAPI) GetMultipart(url string, parameters url.Values,
+	onPart PartCallback, onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	response, cancel := jsonAPI.newRequest("GET", url, parameters, nil, nil).open(
+		onHTTPError, onInternalError)
+	if response == nil {
+		return
+	}
+
+	defer cancel()
+	defer response.Body.Close()
+	if isRedirect(response.StatusCode) {
+		onSuccess()
+		return
+	}
+	mediaType, params, err := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		onInternalError(ErrNotMultipart)
+		return
+	}
+
+	reader := multipart.NewReader(response.Body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+
+		err = onPart(part.Header, part)
+		part.Close()
+		if err != nil {
+			onInternalError(err)
+			return
+		}
+	}
+
+	onSuccess()
+}
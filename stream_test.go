@@ -0,0 +1,217 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetArrayStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("["))
+		for i := 0; i < 1000; i++ {
+			if i > 0 {
+				w.Write([]byte(","))
+			}
+			w.Write([]byte(`{"i":1}`))
+		}
+		w.Write([]byte("]"))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	elements := 0
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.GetArrayStream("/", nil, func(json.RawMessage) error {
+		elements++
+		return nil
+	}, func() {}, onHTTPError, onInternalError)
+	if elements != 1000 {
+		t.Fatalf("elements = %d, want 1000", elements)
+	}
+
+	elements = 0
+	var err error
+	jsonAPI.GetArrayStream("/", nil, func(json.RawMessage) error {
+		elements++
+		if elements == 10 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	}, func() {
+		t.Error("stream succeeded after the callback failed")
+	}, onHTTPError, func(internalErr error) {
+		err = internalErr
+	})
+	if err != io.ErrUnexpectedEOF || elements != 10 {
+		t.Fatalf("err = %v after %d elements, want the callback error after 10", err, elements)
+	}
+}
+
+func TestRequestGetArrayStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "1" || r.URL.Query().Get("q") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`[1,2,3]`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var elements []string
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().SetHeader("X-Test", "1").GetArrayStream("/", url.Values{"q": {"2"}},
+		func(element json.RawMessage) error {
+			elements = append(elements, string(element))
+			return nil
+		}, func() {}, onHTTPError, onInternalError)
+	if !reflect.DeepEqual(elements, []string{"1", "2", "3"}) {
+		t.Fatalf("elements = %q, want the streamed array", elements)
+	}
+}
+
+func TestGetArrayStreamNotArray(t *testing.T) {
+	server := jsonServer(`{"a":1}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var err error
+	jsonAPI.GetArrayStream("/", nil, func(json.RawMessage) error {
+		return nil
+	}, func() {}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if err != ErrNotArray {
+		t.Fatalf("err = %v, want ErrNotArray", err)
+	}
+}
+
+func TestGetChunks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, `{"p":%d}`, i)
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var chunks []string
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.GetChunks("/", nil, func(chunk []byte) error {
+		chunks = append(chunks, string(chunk))
+		return nil
+	}, func() {}, onHTTPError, onInternalError)
+	if strings.Join(chunks, "") != `{"p":0}{"p":1}{"p":2}` || len(chunks) < 2 {
+		t.Fatalf("chunks = %q, want the body in several chunks", chunks)
+	}
+}
+
+func TestGetMultipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writer := multipart.NewWriter(w)
+		w.Header().Set("Content-Type", "multipart/mixed; boundary="+writer.Boundary())
+		part, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json"}})
+		part.Write([]byte(`{"n":"f"}`))
+		part, _ = writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/octet-stream"}})
+		part.Write([]byte{1, 2, 3})
+		writer.Close()
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var parts []string
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.GetMultipart("/", nil, func(header textproto.MIMEHeader, body io.Reader) error {
+		data, err := ioutil.ReadAll(body)
+		parts = append(parts, fmt.Sprint(header.Get("Content-Type"), " ", len(data)))
+		return err
+	}, func() {}, onHTTPError, onInternalError)
+	if strings.Join(parts, ",") != "application/json 9,application/octet-stream 3" {
+		t.Fatalf("parts = %q", parts)
+	}
+}
+
+func TestGetMultipartNotMultipart(t *testing.T) {
+	server := jsonServer(`{}`)
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var err error
+	jsonAPI.GetMultipart("/", nil, func(textproto.MIMEHeader, io.Reader) error {
+		return nil
+	}, func() {}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if err != ErrNotMultipart {
+		t.Fatalf("err = %v, want ErrNotMultipart", err)
+	}
+}
+
+func redirectServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/not-modified" {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		// without a Location the client does not follow the redirect
+		w.Header().Set("Content-Type", "multipart/mixed; boundary=x")
+		w.WriteHeader(http.StatusFound)
+		w.Write([]byte("Found"))
+	}))
+}
+
+func TestStreamsSkipRedirects(t *testing.T) {
+	server := redirectServer()
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	for _, path := range []string{"/not-modified", "/found"} {
+		succeeded := 0
+		onSuccess := func() { succeeded++ }
+		onHTTPError := func(statusCode int, statusMessage, errorMessage string) {
+			t.Errorf("%s: HTTP error %d", path, statusCode)
+		}
+		onInternalError := func(err error) { t.Errorf("%s: %v", path, err) }
+
+		jsonAPI.GetArrayStream(path, nil, func(json.RawMessage) error {
+			t.Errorf("%s: element decoded", path)
+			return nil
+		}, onSuccess, onHTTPError, onInternalError)
+		jsonAPI.GetChunks(path, nil, func([]byte) error {
+			t.Errorf("%s: chunk read", path)
+			return nil
+		}, onSuccess, onHTTPError, onInternalError)
+		jsonAPI.GetMultipart(path, nil, func(textproto.MIMEHeader, io.Reader) error {
+			t.Errorf("%s: part read", path)
+			return nil
+		}, onSuccess, onHTTPError, onInternalError)
+		jsonAPI.Request().GetCSV(path, nil, false, func([]string) error {
+			t.Errorf("%s: row read", path)
+			return nil
+		}, onSuccess, onHTTPError, onInternalError)
+		if succeeded != 4 {
+			t.Errorf("%s: %d successes, want 4", path, succeeded)
+		}
+
+		typed, err := jsonAPI.Request().GetTyped(path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if typed.Kind != KindEmpty || len(typed.Bytes()) != 0 {
+			t.Errorf("%s: typed response %v %q, want empty", path, typed.Kind, typed.Bytes())
+		}
+	}
+}
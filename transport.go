@@ -0,0 +1,76 @@
+package jsonapi
+
+import (
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"time"
+)
+
+// SetResolver sets the resolver used to look up hosts for this JSONAPI
+func (jsonAPI *JSONAPI) SetResolver(resolver *net.Resolver) {
+	jsonAPI.ownTransport()
+	jsonAPI.dialer.Resolver = resolver
+}
+
+// SetDialTimeout sets how long connecting to a host may take, independent
+// of any timeout on the request as a whole
+func (jsonAPI *JSONAPI) SetDialTimeout(timeout time.Duration) {
+	jsonAPI.ownTransport()
+	jsonAPI.dialer.Timeout = timeout
+}
+
+// SetTLSHandshakeTimeout sets how long the TLS handshake may take
+func (jsonAPI *JSONAPI) SetTLSHandshakeTimeout(timeout time.Duration) {
+	jsonAPI.ownTransport()
+	jsonAPI.transport.TLSHandshakeTimeout = timeout
+}
+
+// EnableCookies gives the JSONAPI its own cookie jar, so that cookies set by
+// responses are sent with later requests, a Client with a Jar keeps its own
+func (jsonAPI *JSONAPI) EnableCookies() {
+	jsonAPI.jar, _ = cookiejar.New(nil)
+}
+
+// ownTransport gives the JSONAPI its own transport instead of the shared
+// package client, so that dialing can be configured per instance
+func (jsonAPI *JSONAPI) ownTransport() {
+	if jsonAPI.dialer != nil {
+		return
+	}
+
+	jsonAPI.dialer = &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	jsonAPI.transport = http.DefaultTransport.(*http.Transport).Clone()
+	jsonAPI.transport.DialContext = jsonAPI.dialer.DialContext
+	jsonAPI.transportClient = &http.Client{Transport: jsonAPI.transport}
+}
+
+func (jsonAPI *JSONAPI) httpClient() *http.Client {
+	client := jsonAPI.baseClient()
+	if jsonAPI.jar == nil || client.Jar != nil {
+		return client
+	}
+
+	withJar := *client
+	withJar.Jar = jsonAPI.jar
+	return &withJar
+}
+
+func (jsonAPI *JSONAPI) baseClient() *http.Client {
+	if jsonAPI.Client != nil {
+		if jsonAPI.transport == nil || jsonAPI.Client.Transport != nil {
+			return jsonAPI.Client
+		}
+
+		client := *jsonAPI.Client
+		client.Transport = jsonAPI.transport
+		return &client
+	}
+	if jsonAPI.transportClient != nil {
+		return jsonAPI.transportClient
+	}
+	return client
+}
@@ -0,0 +1,152 @@
+package jsonapi
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetResolver(t *testing.T) {
+	server := jsonServer(`{}`)
+	defer server.Close()
+
+	_, port, _ := net.SplitHostPort(server.Listener.Addr().String())
+	jsonAPI := &JSONAPI{BaseURL: "http://testhost.invalid:" + port}
+	jsonAPI.SetResolver(&net.Resolver{PreferGo: true, Dial: func(context.Context, string, string) (net.Conn, error) {
+		return nil, errors.New("resolver used")
+	}})
+	var err error
+	jsonAPI.Get("/", nil, nil, func() {
+		t.Error("request through the failing resolver succeeded")
+	}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	if err == nil || !strings.Contains(err.Error(), "resolver used") {
+		t.Fatalf("err = %v, want the resolver's error", err)
+	}
+}
+
+func TestSetDialTimeout(t *testing.T) {
+	jsonAPI := &JSONAPI{BaseURL: "http://stalled.invalid"}
+	jsonAPI.SetResolver(&net.Resolver{PreferGo: true, Dial: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}})
+	jsonAPI.SetDialTimeout(50 * time.Millisecond)
+
+	start := time.Now()
+	var err error
+	jsonAPI.GetWithTimeout(5*time.Second, "/", nil, nil, func() {
+		t.Error("stalled dial succeeded")
+	}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	elapsed := time.Since(start)
+	if err == nil || !strings.Contains(err.Error(), "dial tcp") {
+		t.Fatalf("err = %v, want the dial to time out", err)
+	}
+	if elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("dial failed after %v, want the 50ms dial timeout", elapsed)
+	}
+}
+
+func TestSetDialTimeoutSlowServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(100 * time.Millisecond):
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetDialTimeout(50 * time.Millisecond)
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.GetWithTimeout(time.Second, "/", nil, nil, func() {}, onHTTPError, onInternalError)
+
+	var err error
+	jsonAPI.GetWithTimeout(20*time.Millisecond, "/", nil, nil, func() {
+		t.Error("request outlived its timeout")
+	}, onHTTPError, func(internalErr error) {
+		err = internalErr
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want the overall timeout", err)
+	}
+}
+
+func TestSetTLSHandshakeTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		// accept connections but never answer the TLS handshake
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	jsonAPI := &JSONAPI{BaseURL: "https://" + listener.Addr().String()}
+	jsonAPI.SetTLSHandshakeTimeout(50 * time.Millisecond)
+	start := time.Now()
+	jsonAPI.GetWithTimeout(5*time.Second, "/", nil, nil, func() {
+		t.Error("request without a handshake succeeded")
+	}, nil, func(internalErr error) {
+		err = internalErr
+	})
+	elapsed := time.Since(start)
+	if err == nil || !strings.Contains(err.Error(), "TLS handshake timeout") {
+		t.Fatalf("err = %v, want a TLS handshake timeout", err)
+	}
+	if elapsed > time.Second {
+		t.Fatalf("handshake failed after %v, want the 50ms handshake timeout", elapsed)
+	}
+}
+
+func TestEnableCookies(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "s1", Path: "/"})
+			return
+		}
+		cookie, err := r.Cookie("session")
+		if err != nil || cookie.Value != "s1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"me":"u"}`))
+	}))
+	defer server.Close()
+
+	plain := &JSONAPI{BaseURL: server.URL}
+	plain.Request().PostJSON("/login", nil, nil, nil)
+	if err := plain.Request().GetJSON("/me", nil, nil); err == nil {
+		t.Fatal("cookie kept without EnableCookies")
+	}
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.EnableCookies()
+	if err := jsonAPI.Request().PostJSON("/login", nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	var result struct{ Me string }
+	if err := jsonAPI.Request().GetJSON("/me", nil, &result); err != nil || result.Me != "u" {
+		t.Fatalf("err = %v, result = %+v", err, result)
+	}
+
+	other := &JSONAPI{BaseURL: server.URL}
+	if err := other.Request().GetJSON("/me", nil, nil); err == nil {
+		t.Fatal("cookie jar shared between JSONAPIs")
+	}
+}
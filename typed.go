@@ -0,0 +1,105 @@
+package jsonapi
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Kind is the kind of content of a TypedResponse
+type Kind int
+
+const (
+	// KindEmpty is a response without a body
+	KindEmpty Kind = iota
+	// KindJSON is a JSON response
+	KindJSON
+	// KindText is a text or XML response
+	KindText
+	// KindBinary is any other response
+	KindBinary
+)
+
+// TypedResponse is a response body together with the kind of its content
+type TypedResponse struct {
+	Kind        Kind
+	ContentType string
+	body        []byte
+}
+
+// JSON returns the body of a KindJSON response, or nil
+func (response *TypedResponse) JSON() json.RawMessage {
+	if response.Kind != KindJSON {
+		return nil
+	}
+	return response.body
+}
+
+// Text returns the body of a KindText response, or ""
+func (response *TypedResponse) Text() string {
+	if response.Kind != KindText {
+		return ""
+	}
+	return string(response.body)
+}
+
+// Bytes returns the body of any kind of response
+func (response *TypedResponse) Bytes() []byte {
+	return response.body
+}
+
+// GetTyped request, returning the body with its kind of content told by the
+// Content-Type, or sniffed when there is none, redirects that were not
+// followed and 304 Not Modified are KindEmpty
+func (r *Request) GetTyped(url string, parameters url.Values) (*TypedResponse, error) {
+	r.method = "GET"
+	r.path = url
+	r.parameters = parameters
+	var err error
+	response, cancel := r.open(r.errorCallbacks(&err))
+	if response == nil {
+		return nil, err
+	}
+
+	defer cancel()
+	defer response.Body.Close()
+	if isRedirect(response.StatusCode) {
+		return &TypedResponse{Kind: KindEmpty}, nil
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := response.Header.Get("Content-Type")
+	return &TypedResponse{
+		Kind:        contentKind(contentType, body),
+		ContentType: contentType,
+		body:        body,
+	}, nil
+}
+
+func contentKind(contentType string, body []byte) Kind {
+	if len(body) == 0 {
+		return KindEmpty
+	}
+	if contentType == "" {
+		if json.Valid(trimLeading(body)) {
+			return KindJSON
+		}
+		contentType = http.DetectContentType(body)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		return KindJSON
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+xml"):
+		return KindText
+	}
+	return KindBinary
+}
@@ -0,0 +1,62 @@
+package jsonapi
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetTyped(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/vnd.api+json")
+			w.Write([]byte(`{"a":1}`))
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("hi"))
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte{0, 1, 2})
+		case "/sniff":
+			w.Header()["Content-Type"] = nil
+			w.Write([]byte(`[1]`))
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	get := func(path string) *TypedResponse {
+		typed, err := jsonAPI.Request().GetTyped(path, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		return typed
+	}
+	if typed := get("/json"); typed.Kind != KindJSON || string(typed.JSON()) != `{"a":1}` || typed.Text() != "" {
+		t.Errorf("/json = %+v", typed)
+	}
+	if typed := get("/text"); typed.Kind != KindText || typed.Text() != "hi" || typed.JSON() != nil {
+		t.Errorf("/text = %+v", typed)
+	}
+	if typed := get("/binary"); typed.Kind != KindBinary || !bytes.Equal(typed.Bytes(), []byte{0, 1, 2}) {
+		t.Errorf("/binary = %+v", typed)
+	}
+	if typed := get("/sniff"); typed.Kind != KindJSON {
+		t.Errorf("/sniff = %+v", typed)
+	}
+	if typed := get("/empty"); typed.Kind != KindEmpty || len(typed.Bytes()) != 0 {
+		t.Errorf("/empty = %+v", typed)
+	}
+
+	var httpErr *HTTPError
+	if _, err := jsonAPI.Request().GetTyped("/error", nil); !errors.As(err, &httpErr) {
+		t.Fatalf("err = %v, want an HTTPError", err)
+	}
+}
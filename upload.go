@@ -0,0 +1,105 @@
+package jsonapi
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MultipartPart is a file part of a PostMultipart body, FileName defaults to
+// the base name of a Reader with a Name method like *os.File and to
+// FieldName otherwise, ContentType defaults to application/octet-stream
+type MultipartPart struct {
+	FieldName   string
+	FileName    string
+	ContentType string
+	Reader      io.Reader
+}
+
+// PostMultipart request, sending fields and then parts in order as a
+// multipart/form-data body
+func (r *Request) PostMultipart(url string, parameters url.Values,
+	fields map[string]string, parts []MultipartPart, responseBody interface{},
+	onSuccess SuccessCallback, onHTTPError HTTPErrorCallback,
+	onInternalError InternalErrorCallback) {
+	body, contentType, err := multipartBody(fields, parts)
+	if err != nil {
+		onInternalError(err)
+		return
+	}
+
+	r.method = "POST"
+	r.path = url
+	r.parameters = parameters
+	r.requestBody = body
+	r.responseBody = responseBody
+	r.contentType = contentType
+	r.execute(onSuccess, onHTTPError, onInternalError)
+}
+
+func multipartBody(fields map[string]string,
+	parts []MultipartPart) ([]byte, string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, name := range sortedKeys(fields) {
+		err := writer.WriteField(name, fields[name])
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	for _, part := range parts {
+		writerPart, err := writer.CreatePart(part.header())
+		if err != nil {
+			return nil, "", err
+		}
+		_, err = io.Copy(writerPart, part.Reader)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	err := writer.Close()
+	if err != nil {
+		return nil, "", err
+	}
+	return body.Bytes(), writer.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// header returns the part's MIME header, filling in the defaults
+func (part MultipartPart) header() textproto.MIMEHeader {
+	fileName := part.FileName
+	if fileName == "" {
+		fileName = part.FieldName
+		if named, ok := part.Reader.(interface{ Name() string }); ok {
+			fileName = filepath.Base(named.Name())
+		}
+	}
+	contentType := part.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(part.FieldName), quoteEscaper.Replace(fileName)))
+	header.Set("Content-Type", contentType)
+	return header
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
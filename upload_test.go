@@ -0,0 +1,123 @@
+package jsonapi
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestPostMultipart(t *testing.T) {
+	var parts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		file, header, err := r.FormFile("upload")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		data, _ := ioutil.ReadAll(file)
+		parts = append(parts, r.FormValue("title"), header.Filename, string(data), r.URL.Query().Get("q"))
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var result struct{ OK bool }
+	done := false
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().PostMultipart("/upload", url.Values{"q": {"1"}},
+		map[string]string{"title": "hello"},
+		[]MultipartPart{{FieldName: "upload", Reader: strings.NewReader("file data")}},
+		&result, func() {
+			done = true
+		}, onHTTPError, onInternalError)
+	if !done || !result.OK || !reflect.DeepEqual(parts, []string{"hello", "upload", "file data", "1"}) {
+		t.Fatalf("done = %v, result = %+v, parts = %q", done, result, parts)
+	}
+
+	var err error
+	jsonAPI.Request().PostMultipart("/upload", nil, nil,
+		[]MultipartPart{{FieldName: "upload", Reader: errReader{}}}, nil,
+		func() {
+			t.Error("failed upload succeeded")
+		}, onHTTPError, func(internalErr error) {
+			err = internalErr
+		})
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("err = %v, want the reader's error", err)
+	}
+}
+
+func TestPostMultipartIgnoresHeadersContentType(t *testing.T) {
+	var title string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Content-Type %q: %v", r.Header.Get("Content-Type"), err)
+			return
+		}
+		title = r.FormValue("title")
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, Headers: map[string]string{"Content-Type": "application/json"}}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().PostMultipart("/upload", nil, map[string]string{"title": "hello"},
+		[]MultipartPart{{FieldName: "upload", Reader: strings.NewReader("file data")}},
+		nil, func() {}, onHTTPError, onInternalError)
+	if title != "hello" {
+		t.Fatalf("title = %q, want the multipart body to be parsed", title)
+	}
+}
+
+func TestPostMultipartPartHeaders(t *testing.T) {
+	var parts []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reader, err := r.MultipartReader()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			data, _ := ioutil.ReadAll(part)
+			parts = append(parts, part.FormName()+"|"+part.FileName()+"|"+
+				part.Header.Get("Content-Type")+"|"+string(data))
+		}
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Request().PostMultipart("/upload", nil, nil, []MultipartPart{
+		{FieldName: "image", FileName: "a.png", ContentType: "image/png", Reader: strings.NewReader("png")},
+		{FieldName: "data", Reader: strings.NewReader("raw")},
+	}, nil, func() {}, onHTTPError, onInternalError)
+	want := []string{
+		"image|a.png|image/png|png",
+		"data|data|application/octet-stream|raw",
+	}
+	if !reflect.DeepEqual(parts, want) {
+		t.Fatalf("parts = %q, want %q", parts, want)
+	}
+}
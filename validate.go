@@ -0,0 +1,53 @@
+package jsonapi
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Validate checks the configuration for mistakes that would otherwise only
+// show up once requests are sent, a RetryPolicy that retries POST is valid
+// with any body as io.Reader bodies, which can only be read once, are never
+// retried
+func (jsonAPI *JSONAPI) Validate() error {
+	if jsonAPI.BaseURL == "" {
+		return errors.New("jsonapi: BaseURL is empty")
+	}
+
+	baseURL, err := url.Parse(jsonAPI.BaseURL)
+	if err != nil {
+		return fmt.Errorf("jsonapi: BaseURL is invalid: %v", err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return fmt.Errorf("jsonapi: BaseURL %q must use http or https", jsonAPI.BaseURL)
+	}
+	if baseURL.Host == "" {
+		return fmt.Errorf("jsonapi: BaseURL %q has no host", jsonAPI.BaseURL)
+	}
+
+	headers, _, _ := jsonAPI.hooks()
+	for name := range headers {
+		if name == "" {
+			return errors.New("jsonapi: Headers contains an empty header name")
+		}
+	}
+
+	if jsonAPI.maxJSONDepth < 0 {
+		return fmt.Errorf("jsonapi: max JSON depth %d is negative", jsonAPI.maxJSONDepth)
+	}
+	if jsonAPI.hedgingMaxExtra < 0 || jsonAPI.hedgingDelay < 0 {
+		return errors.New("jsonapi: hedging delay and extra requests must not be negative")
+	}
+	if jsonAPI.hedgingMaxExtra > 0 && jsonAPI.hedgingDelay == 0 {
+		return errors.New("jsonapi: hedging is enabled without a delay")
+	}
+	if jsonAPI.dialer != nil && jsonAPI.dialer.Timeout < 0 {
+		return errors.New("jsonapi: dial timeout is negative")
+	}
+	if policy := jsonAPI.RetryPolicy; policy != nil &&
+		(policy.MaxRetries < 0 || policy.BaseDelay < 0) {
+		return errors.New("jsonapi: retry count and delay must not be negative")
+	}
+	return nil
+}
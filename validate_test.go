@@ -0,0 +1,95 @@
+package jsonapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		valid   bool
+	}{
+		{"", false},
+		{"://x", false},
+		{"ftp://x", false},
+		{"https:///v1", false},
+		{"https://api.example.com/v1", true},
+	}
+	for _, test := range tests {
+		err := (&JSONAPI{BaseURL: test.baseURL}).Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("Validate(%q) = %v", test.baseURL, err)
+		}
+	}
+
+	jsonAPI := &JSONAPI{BaseURL: "https://api.example.com"}
+	jsonAPI.SetHedging(0, 2)
+	if jsonAPI.Validate() == nil {
+		t.Fatal("hedging without a delay is valid")
+	}
+}
+
+func TestValidateRetryPOSTWithReader(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL, RetryPolicy: &RetryPolicy{
+		MaxRetries: 2,
+		RetryPOST:  true,
+	}}
+	jsonAPI.SetClock(&fakeClock{})
+	if err := jsonAPI.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want retrying POST to be valid", err)
+	}
+	if err := jsonAPI.Request().PostJSON("/", nil, strings.NewReader("{}"), nil); err == nil || attempts != 1 {
+		t.Fatalf("io.Reader body: err = %v after %d attempts, want no retry", err, attempts)
+	}
+	attempts = 0
+	if err := jsonAPI.Request().PostJSON("/", nil, struct{}{}, nil); err == nil || attempts != 3 {
+		t.Fatalf("marshaled body: err = %v after %d attempts, want 3", err, attempts)
+	}
+}
+
+func TestRequestValidator(t *testing.T) {
+	hits := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	type item struct{ Name string }
+	errInvalid := errors.New("name required")
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	jsonAPI.SetRequestValidator(func(body interface{}) error {
+		if it, ok := body.(item); ok && it.Name == "" {
+			return errInvalid
+		}
+		return nil
+	})
+
+	var err error
+	jsonAPI.Post("/", nil, item{}, nil, func() {
+		t.Error("invalid body succeeded")
+	}, func(int, string, string) {
+		t.Error("invalid body was sent")
+	}, func(internalErr error) {
+		err = internalErr
+	})
+	if err != errInvalid || hits != 0 {
+		t.Fatalf("err = %v after %d requests", err, hits)
+	}
+	if err := jsonAPI.Request().PostJSON("/", nil, item{Name: "x"}, nil); err != nil || hits != 1 {
+		t.Fatalf("valid body: err = %v after %d requests", err, hits)
+	}
+	if err := jsonAPI.Request().GetJSON("/", nil, nil); err != nil || hits != 2 {
+		t.Fatalf("no body: err = %v after %d requests", err, hits)
+	}
+}
@@ -0,0 +1,82 @@
+package jsonapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// WarningCallback runs for every warning in a response's Warning headers
+type WarningCallback func(code int, agent, text string)
+
+// OnWarning sets a callback that runs for every warning a response carries
+func (jsonAPI *JSONAPI) OnWarning(onWarning WarningCallback) {
+	jsonAPI.onWarning = onWarning
+}
+
+func handleWarnings(response *http.Response, onWarning WarningCallback) {
+	for _, header := range response.Header["Warning"] {
+		for header != "" {
+			var code int
+			var agent, text string
+			var ok bool
+			code, agent, text, header, ok = parseWarning(header)
+			if !ok {
+				break
+			}
+			onWarning(code, agent, text)
+		}
+	}
+}
+
+// parseWarning parses the first warning-value of an RFC 7234 Warning header
+// and returns the remaining header
+func parseWarning(header string) (code int, agent, text, rest string, ok bool) {
+	header = strings.TrimLeft(header, " ,")
+	fields := strings.SplitN(header, " ", 3)
+	if len(fields) != 3 {
+		return 0, "", "", "", false
+	}
+
+	code, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, "", "", "", false
+	}
+
+	agent = fields[1]
+	text, rest, ok = parseQuotedString(fields[2])
+	if !ok {
+		return 0, "", "", "", false
+	}
+
+	rest = strings.TrimLeft(rest, " ")
+	if strings.HasPrefix(rest, "\"") {
+		_, rest, ok = parseQuotedString(rest)
+		if !ok {
+			return 0, "", "", "", false
+		}
+	}
+	return code, agent, text, rest, true
+}
+
+func parseQuotedString(s string) (value, rest string, ok bool) {
+	if !strings.HasPrefix(s, "\"") {
+		return "", "", false
+	}
+
+	var builder strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) {
+				builder.WriteByte(s[i])
+			}
+		case '"':
+			return builder.String(), s[i+1:], true
+		default:
+			builder.WriteByte(s[i])
+		}
+	}
+	return "", "", false
+}
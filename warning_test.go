@@ -0,0 +1,33 @@
+package jsonapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOnWarning(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Warning", `299 api.example.com "Deprecated \"v1\"" "Sat, 25 Aug 2012 23:34:45 GMT", 110 - "Response is Stale"`)
+		w.Header().Add("Warning", `199 proxy "Misc"`)
+	}))
+	defer server.Close()
+
+	jsonAPI := &JSONAPI{BaseURL: server.URL}
+	var warnings []string
+	jsonAPI.OnWarning(func(code int, agent, text string) {
+		warnings = append(warnings, fmt.Sprint(code, "|", agent, "|", text))
+	})
+	onHTTPError, onInternalError := failOnError(t)
+	jsonAPI.Get("/", nil, nil, func() {}, onHTTPError, onInternalError)
+	want := []string{
+		`299|api.example.com|Deprecated "v1"`,
+		"110|-|Response is Stale",
+		"199|proxy|Misc",
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Fatalf("warnings = %q, want %q", warnings, want)
+	}
+}